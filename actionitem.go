@@ -0,0 +1,348 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Action item statuses.
+const (
+	ActionItemOpen      = "open"
+	ActionItemDone      = "done"
+	ActionItemCancelled = "cancelled"
+)
+
+// Block IDs of the action item inputs in the Ack form.
+const (
+	ActionItemDescriptionBlock = "action_item_description"
+	ActionItemOwnerBlock       = "action_item_owner"
+	ActionItemDueDateBlock     = "action_item_due_date"
+)
+
+// actionItemReminderInterval is how often an overdue action item is reminded again.
+const actionItemReminderInterval = 24 * time.Hour
+
+// ActionItem is a remediation task attached to an incident.
+type ActionItem struct {
+	ID          string    `json:"id"`
+	IncidentID  string    `json:"incident_id"`
+	Channel     string    `json:"channel"`
+	Team        string    `json:"team"`
+	Owner       string    `json:"owner"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	DueDate     time.Time `json:"due_date"`
+	CreatedAt   time.Time `json:"created_at"`
+	RemindedAt  time.Time `json:"reminded_at,omitempty"`
+}
+
+// Overdue reports whether the item is still open past its due date.
+func (a ActionItem) Overdue(now time.Time) bool {
+	return a.Status == ActionItemOpen && !a.DueDate.IsZero() && now.After(a.DueDate)
+}
+
+type actionItemRepository interface {
+	InsertActionItem(ctx context.Context, item ActionItem) (ActionItem, error)
+	UpdateActionItem(ctx context.Context, item ActionItem) error
+	GetActionItem(ctx context.Context, id string) (ActionItem, error)
+	GetActionItemsByIncident(ctx context.Context, incidentID, channel string) ([]ActionItem, error)
+	GetOpenActionItems(ctx context.Context) ([]ActionItem, error)
+	DeleteActionItems(ctx context.Context, incidentID, channel string) error
+}
+
+// WithActionItems replaces the default in-memory action item store, e.g. with
+// a durable repository.SQLRepository.
+func WithActionItems(items actionItemRepository) Option {
+	return func(u *UseCase) {
+		u.actionItems = items
+	}
+}
+
+// MemoryActionItems is an in-process actionItemRepository. Items are lost on
+// restart; use a durable repository.SQLRepository in production.
+type MemoryActionItems struct {
+	mu    sync.RWMutex
+	seq   int
+	items map[string]ActionItem
+}
+
+func NewMemoryActionItems() *MemoryActionItems {
+	return &MemoryActionItems{
+		items: map[string]ActionItem{},
+	}
+}
+
+func (m *MemoryActionItems) InsertActionItem(ctx context.Context, item ActionItem) (ActionItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	item.ID = strconv.Itoa(m.seq)
+	m.items[item.ID] = item
+	return item, nil
+}
+
+func (m *MemoryActionItems) UpdateActionItem(ctx context.Context, item ActionItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[item.ID]; !ok {
+		return fmt.Errorf("action item %s: %w", item.ID, ErrNotFound)
+	}
+	m.items[item.ID] = item
+	return nil
+}
+
+func (m *MemoryActionItems) GetActionItem(ctx context.Context, id string) (ActionItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[id]
+	if !ok {
+		return ActionItem{}, fmt.Errorf("action item %s: %w", id, ErrNotFound)
+	}
+	return item, nil
+}
+
+func (m *MemoryActionItems) GetActionItemsByIncident(ctx context.Context, incidentID, channel string) ([]ActionItem, error) {
+	return m.filter(func(item ActionItem) bool { return item.IncidentID == incidentID && item.Channel == channel }), nil
+}
+
+func (m *MemoryActionItems) GetOpenActionItems(ctx context.Context) ([]ActionItem, error) {
+	return m.filter(func(item ActionItem) bool { return item.Status == ActionItemOpen }), nil
+}
+
+func (m *MemoryActionItems) DeleteActionItems(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, item := range m.items {
+		if item.IncidentID == incidentID && item.Channel == channel {
+			delete(m.items, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryActionItems) filter(match func(ActionItem) bool) []ActionItem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := []ActionItem{}
+	for _, item := range m.items {
+		if match(item) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items
+}
+
+// ActionItemBlocks returns the optional action item inputs appended to the Ack form.
+func ActionItemBlocks() []slack.Block {
+	description := slack.NewInputBlock(
+		ActionItemDescriptionBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Follow-up action item", false, false),
+		nil,
+		slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "What needs to be fixed?", false, false), ActionItemDescriptionBlock),
+	)
+	description.Optional = true
+
+	owner := slack.NewInputBlock(
+		ActionItemOwnerBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Action item owner", false, false),
+		nil,
+		slack.NewOptionsSelectBlockElement(slack.OptTypeUser, slack.NewTextBlockObject(slack.PlainTextType, "Select owner", false, false), ActionItemOwnerBlock),
+	)
+	owner.Optional = true
+
+	dueDate := slack.NewInputBlock(
+		ActionItemDueDateBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Due date", false, false),
+		nil,
+		slack.NewDatePickerBlockElement(ActionItemDueDateBlock),
+	)
+	dueDate.Optional = true
+
+	return []slack.Block{description, owner, dueDate}
+}
+
+// CreateActionItem attaches an action item to an incident and announces it in the incident thread.
+func (u *UseCase) CreateActionItem(ctx context.Context, item ActionItem) (ActionItem, error) {
+	if item.Description == "" {
+		return ActionItem{}, fmt.Errorf("action item description is required")
+	}
+
+	incident, err := u.slackRepo.GetNewRelicIncident(ctx, item.IncidentID, item.Channel)
+	if err != nil {
+		return ActionItem{}, err
+	}
+
+	if item.Team == "" {
+		item.Team = incident.Owner
+	}
+	item.Status = ActionItemOpen
+	item.CreatedAt = time.Now()
+
+	item, err = u.actionItems.InsertActionItem(ctx, item)
+	if err != nil {
+		return ActionItem{}, err
+	}
+
+	message := fmt.Sprintf(":memo: *Action item #%s* : %s\n*Owner* : %s\n*Due* : %s", item.ID, item.Description, orPlaceholder(mention(item.Owner)), formatDate(item.DueDate))
+	_, _, err = u.slackRepo.ReplyMessageInThread(ctx, item.Channel, message, u.GetColorStr(incident.Status, incident.Severity), incident.MessageTimestamp, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", item.Channel, err)
+	}
+
+	return item, nil
+}
+
+// UpdateActionItemStatus marks an action item as open, done or cancelled on
+// behalf of actor and announces it in the incident thread. Done and
+// cancelled items are no longer reminded and let the incident be archived.
+func (u *UseCase) UpdateActionItemStatus(ctx context.Context, id, status, actor string) (ActionItem, error) {
+	switch status {
+	case ActionItemOpen, ActionItemDone, ActionItemCancelled:
+	default:
+		return ActionItem{}, fmt.Errorf("invalid action item status %q", status)
+	}
+
+	item, err := u.actionItems.GetActionItem(ctx, id)
+	if err != nil {
+		return ActionItem{}, err
+	}
+	if item.Status == status {
+		return item, nil
+	}
+
+	item.Status = status
+	if err := u.actionItems.UpdateActionItem(ctx, item); err != nil {
+		return ActionItem{}, err
+	}
+
+	incident, err := u.slackRepo.GetNewRelicIncident(ctx, item.IncidentID, item.Channel)
+	if err != nil {
+		log.Errorf("Error GET incident on database: %s", err)
+		return item, nil
+	}
+	message := fmt.Sprintf(":memo: %s marked *action item #%s* as %s: %s", mention(actor), item.ID, status, item.Description)
+	_, _, err = u.slackRepo.ReplyMessageInThread(ctx, item.Channel, message, u.GetColorStr(incident.Status, incident.Severity), incident.MessageTimestamp, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", item.Channel, err)
+	}
+
+	return item, nil
+}
+
+// SendActionItemReminders posts a reminder in the incident thread for every
+// overdue action item, at most once per actionItemReminderInterval.
+func (u *UseCase) SendActionItemReminders(ctx context.Context, now time.Time) error {
+	items, err := u.actionItems.GetOpenActionItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if !item.Overdue(now) || now.Sub(item.RemindedAt) < actionItemReminderInterval {
+			continue
+		}
+
+		incident, err := u.slackRepo.GetNewRelicIncident(ctx, item.IncidentID, item.Channel)
+		if err != nil {
+			log.Errorf("Error GET incident on database: %s", err)
+			continue
+		}
+
+		message := fmt.Sprintf(":alarm_clock: *Action item #%s is overdue* (due %s)\n%s\n*Owner* : %s", item.ID, formatDate(item.DueDate), item.Description, orPlaceholder(mention(item.Owner)))
+		_, _, err = u.slackRepo.ReplyMessageInThread(ctx, item.Channel, message, u.GetColorStr("open", incident.Severity), incident.MessageTimestamp, incident.URL)
+		if err != nil {
+			log.Errorf("Failed send slack message to channel %s because: %s", item.Channel, err)
+			continue
+		}
+
+		item.RemindedAt = now
+		if err := u.actionItems.UpdateActionItem(ctx, item); err != nil {
+			log.Errorf("Error store action item to database: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// RunActionItemReminders calls SendActionItemReminders every interval until ctx is done.
+func (u *UseCase) RunActionItemReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := u.SendActionItemReminders(ctx, now); err != nil {
+				log.Errorf("Failed send action item reminders because: %s", err)
+			}
+		}
+	}
+}
+
+// OverdueActionItems returns overdue action items grouped by owning team.
+func (u *UseCase) OverdueActionItems(ctx context.Context, now time.Time) (map[string][]ActionItem, error) {
+	items, err := u.actionItems.GetOpenActionItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := map[string][]ActionItem{}
+	for _, item := range items {
+		if item.Overdue(now) {
+			report[item.Team] = append(report[item.Team], item)
+		}
+	}
+
+	return report, nil
+}
+
+// actionItemFromForm extracts an optional action item from the Ack form view state.
+func actionItemFromForm(values map[string]map[string]slack.BlockAction) (ActionItem, bool) {
+	item := ActionItem{}
+
+	for blockID, state := range values {
+		for _, value := range state {
+			switch blockID {
+			case ActionItemDescriptionBlock:
+				item.Description = value.Value
+			case ActionItemOwnerBlock:
+				item.Owner = value.SelectedUser
+			case ActionItemDueDateBlock:
+				if due, err := time.ParseInLocation("2006-01-02", value.SelectedDate, time.Local); err == nil {
+					item.DueDate = due.Add(24*time.Hour - time.Second)
+				}
+			}
+		}
+	}
+
+	return item, item.Description != ""
+}
+
+func isActionItemBlock(blockID string) bool {
+	return blockID == ActionItemDescriptionBlock || blockID == ActionItemOwnerBlock || blockID == ActionItemDueDateBlock
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format("2006-01-02")
+}
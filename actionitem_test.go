@@ -0,0 +1,69 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActionItemStatusAPI(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t, WithAuthenticator(func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") != "Bearer alice" {
+			return "", errors.New("unknown token")
+		}
+		return "U0ALICE01", nil
+	}))
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	item, err := u.CreateActionItem(ctx, ActionItem{IncidentID: "1", Channel: "C1", Description: "Add disk alerts", DueDate: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateActionItem: %v", err)
+	}
+
+	post := func(id, body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/action-items/"+id+"/status", strings.NewReader(body))
+		if authenticated {
+			req.Header.Set("Authorization", "Bearer alice")
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(item.ID, `{"status": "done"}`, false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status update without token = %d, want 401", rec.Code)
+	}
+	if rec := post("404", `{"status": "done"}`, true); rec.Code != http.StatusNotFound {
+		t.Errorf("status update of unknown item = %d, want 404", rec.Code)
+	}
+	if rec := post(item.ID, `{"status": "finished"}`, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid status = %d, want 400", rec.Code)
+	}
+	if rec := post(item.ID, `{"status": "done"}`, true); rec.Code != http.StatusOK {
+		t.Fatalf("status update = %d %s", rec.Code, rec.Body)
+	}
+
+	if got, _ := u.actionItems.GetActionItem(ctx, item.ID); got.Status != ActionItemDone {
+		t.Errorf("action item status = %q, want done", got.Status)
+	}
+	replies := repo.Calls("ReplyMessageInThread")
+	if last := replies[len(replies)-1]; !strings.Contains(last.Text, "<@U0ALICE01> marked *action item #"+item.ID+"* as done") {
+		t.Errorf("status reply = %q", last.Text)
+	}
+
+	// A done item is overdue no longer, so it is not reminded
+	before := len(repo.Calls("ReplyMessageInThread"))
+	if err := u.SendActionItemReminders(ctx, time.Now()); err != nil {
+		t.Fatalf("SendActionItemReminders: %v", err)
+	}
+	if after := len(repo.Calls("ReplyMessageInThread")); after != before {
+		t.Errorf("done action item was reminded")
+	}
+}
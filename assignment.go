@@ -0,0 +1,227 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// EventAssigned is recorded on every assignment and handoff.
+const EventAssigned = "assigned"
+
+// TakeOwnershipAction is the action ID of the "Take ownership" button.
+const TakeOwnershipAction = "take_ownership"
+
+// Assignment is one entry of an incident's assignment history.
+type Assignment struct {
+	IncidentID string    `json:"incident_id"`
+	Channel    string    `json:"channel"`
+	Assignee   string    `json:"assignee"`
+	Previous   string    `json:"previous,omitempty"`
+	AssignedBy string    `json:"assigned_by"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+type assignmentRepository interface {
+	InsertAssignment(ctx context.Context, assignment Assignment) error
+	GetAssignments(ctx context.Context, incidentID, channel string) ([]Assignment, error)
+	DeleteAssignments(ctx context.Context, incidentID, channel string) error
+}
+
+// WithAssignments replaces the default in-memory assignment history store,
+// e.g. with a durable repository.SQLRepository.
+func WithAssignments(assignments assignmentRepository) Option {
+	return func(u *UseCase) {
+		u.assignments = assignments
+	}
+}
+
+// MemoryAssignments is an in-process assignmentRepository. The history is
+// lost on restart; use a durable repository.SQLRepository in production.
+type MemoryAssignments struct {
+	mu          sync.RWMutex
+	assignments map[incidentKey][]Assignment
+}
+
+func NewMemoryAssignments() *MemoryAssignments {
+	return &MemoryAssignments{
+		assignments: map[incidentKey][]Assignment{},
+	}
+}
+
+func (m *MemoryAssignments) InsertAssignment(ctx context.Context, assignment Assignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := incidentKey{assignment.IncidentID, assignment.Channel}
+	m.assignments[key] = append(m.assignments[key], assignment)
+	return nil
+}
+
+func (m *MemoryAssignments) GetAssignments(ctx context.Context, incidentID, channel string) ([]Assignment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.assignments[incidentKey{incidentID, channel}]
+	assignments := make([]Assignment, len(history))
+	copy(assignments, history)
+	return assignments, nil
+}
+
+func (m *MemoryAssignments) DeleteAssignments(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.assignments, incidentKey{incidentID, channel})
+	return nil
+}
+
+// OwnershipButton returns the "Take ownership" button posted in new incident threads.
+func OwnershipButton() *slack.ButtonBlockElement {
+	button := slack.NewButtonBlockElement(TakeOwnershipAction, TakeOwnershipAction, slack.NewTextBlockObject(slack.PlainTextType, ":raising_hand: Take ownership", true, false))
+	button.Style = slack.StylePrimary
+	return button
+}
+
+// AssignIncident hands the incident over to assignee. Assigning the current
+// assignee again is a no-op that returns the current assignment.
+func (u *UseCase) AssignIncident(ctx context.Context, incidentID, channel, assignee, actor string) (Assignment, error) {
+	return u.assign(ctx, incidentID, channel, assignee, actor, false)
+}
+
+// assign hands the incident over to assignee, or only assigns it when it is
+// unassigned if unassignedOnly is set, returning the current assignment
+// otherwise. The history is read and appended under the incident's lock, so
+// concurrent assignments are recorded one after the other.
+func (u *UseCase) assign(ctx context.Context, incidentID, channel, assignee, actor string, unassignedOnly bool) (Assignment, error) {
+	assignee = strings.TrimSpace(assignee)
+	if assignee == "" {
+		return Assignment{}, fmt.Errorf("assignee is required")
+	}
+
+	incident, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incidentID, channel)
+	if err != nil {
+		return Assignment{}, fmt.Errorf("incident %s: %w", incidentID, err)
+	}
+
+	unlock := u.assignLocks.lock(incidentID, channel)
+	current, err := u.currentAssignment(ctx, incidentID, channel)
+	if err != nil {
+		unlock()
+		return Assignment{}, err
+	}
+	if current.Assignee == assignee || (unassignedOnly && current.Assignee != "") {
+		unlock()
+		return current, nil
+	}
+
+	assignment := Assignment{
+		IncidentID: incidentID,
+		Channel:    channel,
+		Assignee:   assignee,
+		Previous:   current.Assignee,
+		AssignedBy: actor,
+		AssignedAt: time.Now(),
+	}
+	if err := u.assignments.InsertAssignment(ctx, assignment); err != nil {
+		unlock()
+		return Assignment{}, err
+	}
+
+	detail := mention(assignee)
+	if assignment.Previous != "" {
+		detail = fmt.Sprintf("%s (from %s)", mention(assignee), mention(assignment.Previous))
+	}
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: incidentID,
+		Channel:    channel,
+		Type:       EventAssigned,
+		State:      incident.Status,
+		Actor:      actor,
+		Detail:     detail,
+	})
+	unlock()
+
+	note := fmt.Sprintf(":bust_in_silhouette: %s assigned the incident to %s", mention(actor), mention(assignee))
+	if assignment.Previous != "" {
+		note = fmt.Sprintf(":arrows_counterclockwise: %s handed the incident over from %s to %s", mention(actor), mention(assignment.Previous), mention(assignee))
+	}
+
+	// Update Slack Message with the new assignee
+	if _, err := u.refreshIncident(ctx, incidentID, channel, note); err != nil {
+		log.Errorf("Error GET incident on database: %s", err)
+	}
+
+	return assignment, nil
+}
+
+// TakeOwnership assigns the incident to whoever clicked the "Take ownership" button.
+func (u *UseCase) TakeOwnership(ctx context.Context, message slack.InteractionCallback) (Assignment, error) {
+	channel := message.Container.ChannelID
+	messageTimestamp := message.Message.ThreadTimestamp
+	if messageTimestamp == "" {
+		messageTimestamp = message.Message.Timestamp
+	}
+
+	slackMessage, err := u.slackRepo.GetMessageByTimestamp(ctx, messageTimestamp, channel)
+	if err != nil {
+		return Assignment{}, fmt.Errorf("message %s: %w", messageTimestamp, err)
+	}
+
+	actor := actorOf(message.User)
+	return u.AssignIncident(ctx, slackMessage.IncidentID, channel, actor, actor)
+}
+
+// GetAssignments returns the assignment history of an incident, oldest first.
+func (u *UseCase) GetAssignments(ctx context.Context, incidentID, channel string) ([]Assignment, error) {
+	return u.assignments.GetAssignments(ctx, incidentID, channel)
+}
+
+func (u *UseCase) currentAssignment(ctx context.Context, incidentID, channel string) (Assignment, error) {
+	history, err := u.assignments.GetAssignments(ctx, incidentID, channel)
+	if err != nil || len(history) == 0 {
+		return Assignment{}, err
+	}
+
+	return history[len(history)-1], nil
+}
+
+// currentAssignee returns who owns the incident, or "" when unassigned.
+func (u *UseCase) currentAssignee(ctx context.Context, incidentID, channel string) string {
+	current, err := u.currentAssignment(ctx, incidentID, channel)
+	if err != nil {
+		log.Errorf("Error GET incident assignments on database: %s", err)
+	}
+
+	return current.Assignee
+}
+
+// withAssignee adds the assignee line under the status of a rendered incident message.
+func withAssignee(message, assignee string) string {
+	if assignee == "" {
+		return message
+	}
+
+	line := fmt.Sprintf("*Assignee* : %s\n", mention(assignee))
+	if i := strings.Index(message, "*Incident Time*"); i >= 0 {
+		return message[:i] + line + message[i:]
+	}
+	return message + "\n" + line
+}
+
+// firstAckAssignment assigns an unowned incident to whoever acked it first.
+func (u *UseCase) firstAckAssignment(ctx context.Context, incident entitySlack.Incident, actor string) {
+	if actor == "" {
+		return
+	}
+
+	if _, err := u.assign(ctx, incident.IncidentID, incident.Channel, actor, actor, true); err != nil {
+		log.Errorf("Failed assign incident %s because: %s", incident.IncidentID, err)
+	}
+}
@@ -0,0 +1,135 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAssignmentHandoff(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	opened, _ := u.ProcessIncident(ctx, testPayload("1", "open"))
+
+	if _, err := u.AssignIncident(ctx, "1", "C1", " ", "U0ALICE01"); err == nil {
+		t.Error("AssignIncident accepted an empty assignee")
+	}
+	if _, err := u.AssignIncident(ctx, "404", "C1", "U0ALICE01", "U0ALICE01"); err == nil {
+		t.Error("AssignIncident assigned an unknown incident")
+	}
+
+	if _, err := u.AssignIncident(ctx, "1", "C1", "U0ALICE01", "U0ALICE01"); err != nil {
+		t.Fatalf("AssignIncident(alice): %v", err)
+	}
+	handoff, err := u.AssignIncident(ctx, "1", "C1", "U0BOB0001", "U0ALICE01")
+	if err != nil {
+		t.Fatalf("AssignIncident(bob): %v", err)
+	}
+	if handoff.Previous != "U0ALICE01" || handoff.AssignedBy != "U0ALICE01" {
+		t.Errorf("handoff = %+v, want from alice by alice", handoff)
+	}
+
+	// Assigning the assignee again changes nothing
+	if again, err := u.AssignIncident(ctx, "1", "C1", "U0BOB0001", "U0BOB0001"); err != nil || again != handoff {
+		t.Errorf("AssignIncident(bob) again = %+v, %v; want the handoff", again, err)
+	}
+
+	history, err := u.GetAssignments(ctx, "1", "C1")
+	if err != nil || len(history) != 2 || history[0].Assignee != "U0ALICE01" || history[1].Assignee != "U0BOB0001" {
+		t.Errorf("GetAssignments = %+v, %v; want alice then bob", history, err)
+	}
+	if got := u.currentAssignee(ctx, "1", "C1"); got != "U0BOB0001" {
+		t.Errorf("currentAssignee = %q, want bob", got)
+	}
+
+	var note string
+	for _, call := range repo.Calls("ReplyMessageInThread") {
+		if call.ThreadTimestamp == opened.MessageTimestamp && strings.HasPrefix(call.Text, ":arrows_counterclockwise:") {
+			note = call.Text
+		}
+	}
+	if !strings.Contains(note, "from <@U0ALICE01> to <@U0BOB0001>") {
+		t.Errorf("handoff note = %q", note)
+	}
+}
+
+func TestFirstAckAssigns(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+	opened, _ := u.ProcessIncident(ctx, testPayload("1", "open"))
+
+	if _, _, _, err := u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01")); err != nil {
+		t.Fatalf("AckMessage(alice): %v", err)
+	}
+	if got := u.currentAssignee(ctx, "1", "C1"); got != "U0ALICE01" {
+		t.Fatalf("assignee after first ack = %q, want alice", got)
+	}
+
+	// Later acks keep the owner
+	u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0BOB0001"))
+	if history, _ := u.GetAssignments(ctx, "1", "C1"); len(history) != 1 || history[0].Assignee != "U0ALICE01" {
+		t.Errorf("history after second ack = %+v, want alice only", history)
+	}
+}
+
+func TestConcurrentFirstAcksAssignOnce(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	incident, _ := u.slackRepo.GetNewRelicIncidentByID(ctx, "1", "C1")
+
+	var wg sync.WaitGroup
+	for _, actor := range []string{"U0ALICE01", "U0BOB0001", "U0CAROL01", "U0DAVE001"} {
+		wg.Add(1)
+		go func(actor string) {
+			defer wg.Done()
+			u.firstAckAssignment(ctx, incident, actor)
+		}(actor)
+	}
+	wg.Wait()
+
+	if history, _ := u.GetAssignments(ctx, "1", "C1"); len(history) != 1 || history[0].Previous != "" {
+		t.Errorf("history = %+v, want a single first assignment", history)
+	}
+}
+
+func TestAssignAPIUsesAuthenticatedActor(t *testing.T) {
+	ctx := context.Background()
+	body := `{"channel": "C1", "assignee": "U0BOB0001", "assigned_by": "U0MALLORY"}`
+
+	u, _ := newTestUseCase(t, WithAuthenticator(func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") != "Bearer alice" {
+			return "", errors.New("unknown token")
+		}
+		return "U0ALICE01", nil
+	}))
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/1/assignments", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("assign without token = %d, want 401", rec.Code)
+	}
+	if history, _ := u.GetAssignments(ctx, "1", "C1"); len(history) != 0 {
+		t.Errorf("unauthenticated request assigned %+v", history)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/incidents/1/assignments", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("assign = %d %s", rec.Code, rec.Body)
+	}
+	assignment := Assignment{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &assignment); err != nil || assignment.AssignedBy != "U0ALICE01" || assignment.Assignee != "U0BOB0001" {
+		t.Errorf("assignment = %+v, %v; want bob assigned by the authenticated user", assignment, err)
+	}
+}
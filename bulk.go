@@ -0,0 +1,288 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Bulk operations.
+const (
+	BulkAck       = "ack"
+	BulkResolve   = "resolve"
+	BulkAssign    = "assign"
+	BulkRootCause = "root_cause"
+)
+
+// BulkCallbackID is the callback ID of the bulk operation modal.
+const BulkCallbackID = "bulk_incidents"
+
+// Block IDs of the bulk operation modal inputs.
+const (
+	BulkIncidentsBlock = "bulk_incidents"
+	BulkOperationBlock = "bulk_operation"
+	BulkValueBlock     = "bulk_value"
+)
+
+// bulkModalLimit is the most incidents a Slack static select can offer.
+const bulkModalLimit = 100
+
+// bulkLabelLimit is the most characters Slack accepts in an option label.
+const bulkLabelLimit = 75
+
+// ErrModalUnsupported is returned when the repository cannot open Slack modals.
+var ErrModalUnsupported = errors.New("opening slack modals is not supported by the repository")
+
+// viewRepository is implemented by repositories that can open Slack modals.
+type viewRepository interface {
+	OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error
+}
+
+// BulkRequest applies one operation to several incidents of a channel.
+// Value is the assignee for BulkAssign and the root cause for BulkRootCause.
+// Actor is the authenticated user and is never read from request bodies.
+type BulkRequest struct {
+	Operation   string   `json:"operation"`
+	Channel     string   `json:"channel"`
+	IncidentIDs []string `json:"incident_ids"`
+	Value       string   `json:"value,omitempty"`
+	Actor       string   `json:"-"`
+}
+
+// BulkResult is the outcome of a bulk operation on one incident.
+type BulkResult struct {
+	IncidentID string `json:"incident_id"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (r BulkRequest) validate() error {
+	switch r.Operation {
+	case BulkAck, BulkResolve:
+	case BulkAssign, BulkRootCause:
+		if strings.TrimSpace(r.Value) == "" {
+			return fmt.Errorf("%s needs a value", r.Operation)
+		}
+	default:
+		return fmt.Errorf("invalid bulk operation %q", r.Operation)
+	}
+	if r.Channel == "" {
+		return fmt.Errorf("channel is required")
+	}
+	if len(r.IncidentIDs) == 0 {
+		return fmt.Errorf("no incidents selected")
+	}
+	if r.Actor == "" {
+		return fmt.Errorf("actor is required")
+	}
+
+	return nil
+}
+
+// BulkApply applies the operation to every incident in order. Each incident
+// goes through the same path as a single change, so its message and thread
+// are updated; a failure on one incident does not stop the others.
+func (u *UseCase) BulkApply(ctx context.Context, req BulkRequest) ([]BulkResult, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	results := []BulkResult{}
+	for _, incidentID := range req.IncidentIDs {
+		result := BulkResult{IncidentID: incidentID, OK: true}
+		if err := u.bulkApplyOne(ctx, req, incidentID); err != nil {
+			log.Errorf("Failed bulk %s of incident %s because: %s", req.Operation, incidentID, err)
+			result.OK, result.Error = false, err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (u *UseCase) bulkApplyOne(ctx context.Context, req BulkRequest, incidentID string) error {
+	incident, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incidentID, req.Channel)
+	if err != nil {
+		return fmt.Errorf("incident %s: %w", incidentID, err)
+	}
+
+	switch req.Operation {
+	case BulkAck:
+		if incident.Status != "open" {
+			return fmt.Errorf("incident %s is %s", incidentID, incident.Status)
+		}
+		u.acknowledge(ctx, incident, req.Actor)
+
+		_, err = u.applyState(ctx, incident, "acknowledged", req.Actor, fmt.Sprintf(":eyes: Acknowledged by %s (bulk)", mention(req.Actor)))
+		return err
+	case BulkResolve:
+		if incident.Status == "closed" {
+			return fmt.Errorf("incident %s is already closed", incidentID)
+		}
+
+		_, err = u.applyState(ctx, incident, "closed", req.Actor, fmt.Sprintf(":white_check_mark: Resolved by %s (bulk)", mention(req.Actor)))
+		return err
+	case BulkAssign:
+		_, err = u.AssignIncident(ctx, incidentID, req.Channel, req.Value, req.Actor)
+		return err
+	case BulkRootCause:
+		if err := u.slackRepo.UpdateNewRelicIncidentByID(ctx, req.Value, incidentID); err != nil {
+			return err
+		}
+		u.recordEvent(ctx, IncidentEvent{
+			IncidentID: incidentID,
+			Channel:    req.Channel,
+			Type:       EventRootCause,
+			Actor:      req.Actor,
+			Detail:     req.Value,
+		})
+
+		note := fmt.Sprintf(":mag: Root cause set to *%s* by %s (bulk)", u.GetDataOptions(req.Value), mention(req.Actor))
+		if _, err := u.refreshIncident(ctx, incidentID, req.Channel, note); err != nil {
+			return err
+		}
+		u.publishStatusPage(ctx, incidentID, req.Channel)
+		return nil
+	}
+
+	return nil
+}
+
+// OpenBulkModal opens the bulk operation modal listing the unresolved
+// incidents of channel.
+func (u *UseCase) OpenBulkModal(ctx context.Context, triggerID, channel string) error {
+	views, ok := u.baseRepo.(viewRepository)
+	if !ok {
+		return ErrModalUnsupported
+	}
+
+	incidents, _, err := u.SearchIncidents(ctx, IncidentQuery{Status: []string{"open", "acknowledged"}, Channel: channel, Limit: bulkModalLimit})
+	if err != nil {
+		return err
+	}
+	if len(incidents) == 0 {
+		return fmt.Errorf("no unresolved incidents in channel %s", channel)
+	}
+
+	options := []*slack.OptionBlockObject{}
+	for _, incident := range incidents {
+		label := truncate(fmt.Sprintf("[%s] %s", incident.Status, incident.Name), bulkLabelLimit)
+		options = append(options, slack.NewOptionBlockObject(incident.IncidentID, slack.NewTextBlockObject(slack.PlainTextType, label, false, false), nil))
+	}
+
+	operations := []*slack.OptionBlockObject{}
+	for _, op := range []struct{ value, text string }{
+		{BulkAck, "Acknowledge"},
+		{BulkResolve, "Resolve"},
+		{BulkAssign, "Assign to"},
+		{BulkRootCause, "Set root cause"},
+	} {
+		operations = append(operations, slack.NewOptionBlockObject(op.value, slack.NewTextBlockObject(slack.PlainTextType, op.text, false, false), nil))
+	}
+
+	value := slack.NewInputBlock(
+		BulkValueBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Assignee or root cause", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Needed to assign or set the root cause", false, false),
+		slack.NewPlainTextInputBlockElement(nil, BulkValueBlock),
+	)
+	value.Optional = true
+
+	view := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      BulkCallbackID,
+		PrivateMetadata: channel,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Bulk update", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Apply", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(
+				BulkIncidentsBlock,
+				slack.NewTextBlockObject(slack.PlainTextType, "Incidents", false, false),
+				nil,
+				slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeStatic, slack.NewTextBlockObject(slack.PlainTextType, "Select incidents", false, false), BulkIncidentsBlock, options...),
+			),
+			slack.NewInputBlock(
+				BulkOperationBlock,
+				slack.NewTextBlockObject(slack.PlainTextType, "Operation", false, false),
+				nil,
+				slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, slack.NewTextBlockObject(slack.PlainTextType, "Select operation", false, false), BulkOperationBlock, operations...),
+			),
+			value,
+		}},
+	}
+
+	return views.OpenView(ctx, triggerID, view)
+}
+
+// SubmitBulkModal validates the operation chosen in the bulk operation modal
+// and applies it in the background, so the modal closes within Slack's
+// three-second deadline. The results are posted to the channel when done.
+func (u *UseCase) SubmitBulkModal(ctx context.Context, message slack.InteractionCallback) error {
+	req := BulkRequest{
+		Channel: message.View.PrivateMetadata,
+		Actor:   actorOf(message.User),
+	}
+
+	if message.View.State != nil {
+		for _, value := range message.View.State.Values[BulkIncidentsBlock] {
+			for _, option := range value.SelectedOptions {
+				req.IncidentIDs = append(req.IncidentIDs, option.Value)
+			}
+		}
+		for _, value := range message.View.State.Values[BulkOperationBlock] {
+			req.Operation = value.SelectedOption.Value
+		}
+		for _, value := range message.View.State.Values[BulkValueBlock] {
+			req.Value = strings.TrimSpace(value.Value)
+		}
+	}
+	if req.Operation == BulkRootCause {
+		req.Value = u.ConvertValues(req.Value)
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		results, err := u.BulkApply(ctx, req)
+		if err != nil {
+			log.Errorf("Failed bulk %s because: %s", req.Operation, err)
+			return
+		}
+		u.postBulkResults(ctx, req, results)
+	}()
+
+	return nil
+}
+
+// postBulkResults summarizes a bulk operation in its channel.
+func (u *UseCase) postBulkResults(ctx context.Context, req BulkRequest, results []BulkResult) {
+	lines := []string{}
+	for _, result := range results {
+		if !result.OK {
+			lines = append(lines, fmt.Sprintf(":x: %s: %s", result.IncidentID, result.Error))
+		}
+	}
+	summary := fmt.Sprintf("*Bulk %s* by %s: %d of %d incidents updated", req.Operation, mention(req.Actor), len(results)-len(lines), len(results))
+	lines = append([]string{summary}, lines...)
+
+	if _, _, err := u.slackRepo.SendMessage(ctx, req.Channel, strings.Join(lines, "\n"), "", "", "", ""); err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", req.Channel, err)
+	}
+}
+
+// truncate shortens s to at most limit characters, ending it with "..." when cut.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+
+	return string(runes[:limit-3]) + "..."
+}
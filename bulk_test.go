@@ -0,0 +1,121 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+func TestTruncateKeepsRunes(t *testing.T) {
+	label := truncate("[open] "+strings.Repeat("é", 100), bulkLabelLimit)
+	if !utf8.ValidString(label) {
+		t.Fatalf("truncated label is not valid UTF-8: %q", label)
+	}
+	if n := utf8.RuneCountInString(label); n != bulkLabelLimit || !strings.HasSuffix(label, "...") {
+		t.Errorf("truncated label has %d characters: %q", n, label)
+	}
+	if got := truncate("short", bulkLabelLimit); got != "short" {
+		t.Errorf("truncate(short) = %q", got)
+	}
+}
+
+// bulkCallback is a bulk modal submission by userID.
+func bulkCallback(userID, operation string, incidentIDs ...string) slack.InteractionCallback {
+	callback := slack.InteractionCallback{}
+	callback.User.ID = userID
+	callback.View.PrivateMetadata = "C1"
+
+	selected := []slack.OptionBlockObject{}
+	for _, id := range incidentIDs {
+		selected = append(selected, slack.OptionBlockObject{Value: id})
+	}
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		BulkIncidentsBlock: {BulkIncidentsBlock: {SelectedOptions: selected}},
+		BulkOperationBlock: {BulkOperationBlock: {SelectedOption: slack.OptionBlockObject{Value: operation}}},
+	}}
+	return callback
+}
+
+func TestSubmitBulkModal(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	opened, _ := u.ProcessIncident(ctx, testPayload("1", "open"))
+	u.ProcessIncident(ctx, testPayload("2", "open"))
+
+	if err := u.SubmitBulkModal(ctx, bulkCallback("U0ALICE01", "explode", "1")); err == nil {
+		t.Error("SubmitBulkModal accepted an invalid operation")
+	}
+
+	if err := u.SubmitBulkModal(ctx, bulkCallback("U0ALICE01", BulkAck, "1", "404")); err != nil {
+		t.Fatalf("SubmitBulkModal: %v", err)
+	}
+
+	// The results are posted to the channel once the operation is applied
+	var summary []FakeSlackCall
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if summary = repo.Calls("SendMessage")[2:]; len(summary) > 0 {
+			break
+		}
+	}
+	if len(summary) != 1 || !strings.Contains(summary[0].Text, "1 of 2 incidents updated") || !strings.Contains(summary[0].Text, "404") {
+		t.Fatalf("bulk summary = %+v", summary)
+	}
+
+	incident, _ := repo.GetNewRelicIncidentByMsgTimestamp(ctx, "1", opened.MessageTimestamp)
+	if incident.Status != "acknowledged" || u.currentAssignee(ctx, "1", "C1") != "U0ALICE01" {
+		t.Errorf("incident 1 = %+v, assignee %q", incident, u.currentAssignee(ctx, "1", "C1"))
+	}
+	if !u.hasEvent(ctx, "1", "C1", EventAcknowledged) {
+		t.Error("no acknowledged event recorded")
+	}
+}
+
+func TestBulkAPIUsesAuthenticatedActor(t *testing.T) {
+	ctx := context.Background()
+	body := `{"operation": "ack", "channel": "C1", "incident_ids": ["1"], "actor": "U0MALLORY"}`
+
+	u, _ := newTestUseCase(t)
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/bulk", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bulk without authenticator = %d, want 401", rec.Code)
+	}
+
+	u, _ = newTestUseCase(t, WithAuthenticator(func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") != "Bearer alice" {
+			return "", errors.New("unknown token")
+		}
+		return "U0ALICE01", nil
+	}))
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	mux = http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/bulk", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bulk with unknown token = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/incidents/bulk", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bulk = %d %s", rec.Code, rec.Body)
+	}
+	if got := u.currentAssignee(ctx, "1", "C1"); got != "U0ALICE01" {
+		t.Errorf("assignee = %q, want the authenticated user", got)
+	}
+}
@@ -0,0 +1,286 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// WithCoalescing batches the parent message updates and thread replies of an
+// incident made within window into one UpdateMessage and one
+// ReplyMessageInThread, so alert storms do not hit Slack rate limits. The
+// last update wins and replies of the same color are joined in order; a reply
+// of another color sends the batch first. A failed batched send is returned
+// by the next call for the same message or by FlushMessages. Call
+// FlushMessages on shutdown to send what is still pending.
+func WithCoalescing(window time.Duration) Option {
+	return func(u *UseCase) {
+		u.coalesceWindow = window
+	}
+}
+
+type pendingUpdate struct {
+	ctx     context.Context
+	channel string
+	ts      string
+
+	// Latest parent message, set when an update is pending
+	update                 bool
+	message, color, vendor string
+	updateURL              string
+
+	replies              []string
+	replyColor, replyURL string
+
+	// prev is the batch of the same parent message taken for sending before
+	// this one; done is closed once this batch is sent.
+	prev *pendingUpdate
+	done chan struct{}
+}
+
+// coalescingRepository delays UpdateMessage and ReplyMessageInThread per
+// parent message and sends them once the window of the first call elapses.
+// Batches of a parent message are sent one after the other, in order.
+type coalescingRepository struct {
+	slackRepository
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingUpdate
+	// sending holds the last batch taken for sending per parent message
+	sending map[string]*pendingUpdate
+	// failed holds the errors of batches sent by their window until they are
+	// returned by the next call for the parent message or a flush
+	failed map[string]error
+}
+
+func newCoalescingRepository(next slackRepository, window time.Duration) *coalescingRepository {
+	return &coalescingRepository{
+		slackRepository: next,
+		window:          window,
+		pending:         map[string]*pendingUpdate{},
+		sending:         map[string]*pendingUpdate{},
+		failed:          map[string]error{},
+	}
+}
+
+func coalesceKey(channel, messageTimestamp string) string {
+	return channel + "/" + messageTimestamp
+}
+
+// pendingFor returns the batch of a parent message, starting its window on first use.
+// The caller must hold r.mu.
+func (r *coalescingRepository) pendingFor(ctx context.Context, channel, messageTimestamp string) *pendingUpdate {
+	key := coalesceKey(channel, messageTimestamp)
+	if p, ok := r.pending[key]; ok {
+		return p
+	}
+
+	p := &pendingUpdate{ctx: context.WithoutCancel(ctx), channel: channel, ts: messageTimestamp, done: make(chan struct{})}
+	r.pending[key] = p
+	time.AfterFunc(r.window, func() {
+		// An earlier explicit flush may have taken this batch already
+		r.mu.Lock()
+		current := r.pending[key] == p
+		if current {
+			r.take(key)
+		}
+		r.mu.Unlock()
+
+		if current {
+			r.send(key, p, true)
+		}
+	})
+	return p
+}
+
+// take removes the pending batch of a parent message and queues it behind
+// the batch being sent, if any. The caller must hold r.mu.
+func (r *coalescingRepository) take(key string) *pendingUpdate {
+	p, ok := r.pending[key]
+	if !ok {
+		return nil
+	}
+	delete(r.pending, key)
+
+	p.prev = r.sending[key]
+	r.sending[key] = p
+	return p
+}
+
+// takeError returns and forgets the errors of earlier batches of a parent
+// message. The caller must hold r.mu.
+func (r *coalescingRepository) takeError(key string) error {
+	err := r.failed[key]
+	delete(r.failed, key)
+	return err
+}
+
+func (r *coalescingRepository) UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	if messageTimestamp == "" {
+		return r.slackRepository.UpdateMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.pendingFor(ctx, channel, messageTimestamp)
+	p.update = true
+	p.message, p.color, p.vendor, p.updateURL = message, color, vendor, url
+	return channel, messageTimestamp, r.takeError(coalesceKey(channel, messageTimestamp))
+}
+
+func (r *coalescingRepository) ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error) {
+	if messageTimestamp == "" {
+		return r.slackRepository.ReplyMessageInThread(ctx, channel, message, color, messageTimestamp, url)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Joined replies share one color, so a new color starts a new batch
+	key := coalesceKey(channel, messageTimestamp)
+	if p, ok := r.pending[key]; ok && len(p.replies) > 0 && (p.replyColor != color || p.replyURL != url) {
+		r.take(key)
+		go r.send(key, p, true)
+	}
+
+	p := r.pendingFor(ctx, channel, messageTimestamp)
+	p.replies = append(p.replies, message)
+	p.replyColor, p.replyURL = color, url
+	return channel, "", r.takeError(key)
+}
+
+// SubmitButtonAction and ReplaceMessage rewrite the parent message right
+// away, so pending updates are sent first and cannot overwrite them later.
+func (r *coalescingRepository) SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	if err := r.flush(channel, messageTimestamp); err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", channel, err)
+	}
+	return r.slackRepository.SubmitButtonAction(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal)
+}
+
+func (r *coalescingRepository) ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	if err := r.flush(channel, messageTimestamp); err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", channel, err)
+	}
+	return r.slackRepository.ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url, replaceOriginal)
+}
+
+// flush sends the batch of a parent message, if any, and waits until every
+// batch of it is sent. It returns the errors of the batches not reported yet.
+func (r *coalescingRepository) flush(channel, messageTimestamp string) error {
+	return r.flushKey(coalesceKey(channel, messageTimestamp))
+}
+
+func (r *coalescingRepository) flushKey(key string) error {
+	r.mu.Lock()
+	p := r.take(key)
+	last := r.sending[key]
+	r.mu.Unlock()
+
+	var err error
+	if p != nil {
+		err = r.send(key, p, false)
+	} else if last != nil {
+		<-last.done
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.takeError(key), err)
+}
+
+// send sends a batch once the batch before it is sent. Errors are returned,
+// or kept for the next call when keep is set; callers log them.
+func (r *coalescingRepository) send(key string, p *pendingUpdate, keep bool) error {
+	if p.prev != nil {
+		<-p.prev.done
+	}
+
+	var errs []error
+	if p.update {
+		if _, _, err := r.slackRepository.UpdateMessage(p.ctx, p.channel, p.message, p.color, p.ts, p.vendor, p.updateURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(p.replies) > 0 {
+		if _, _, err := r.slackRepository.ReplyMessageInThread(p.ctx, p.channel, strings.Join(p.replies, "\n\n"), p.replyColor, p.ts, p.replyURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+
+	// Record the error before waking up flushes waiting for this batch
+	r.mu.Lock()
+	if keep && err != nil {
+		r.failed[key] = errors.Join(r.failed[key], err)
+	}
+	if r.sending[key] == p {
+		delete(r.sending, key)
+	}
+	r.mu.Unlock()
+	close(p.done)
+
+	if keep {
+		return nil
+	}
+	return err
+}
+
+// flushAll sends every pending batch, waits for the batches being sent and
+// returns every error not reported yet.
+func (r *coalescingRepository) flushAll() error {
+	r.mu.Lock()
+	keys := []string{}
+	for key := range r.pending {
+		keys = append(keys, key)
+	}
+	for key := range r.sending {
+		if _, ok := r.pending[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	r.mu.Unlock()
+
+	errs := []error{}
+	for _, key := range keys {
+		errs = append(errs, r.flushKey(key))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.failed {
+		errs = append(errs, r.takeError(key))
+	}
+	return errors.Join(errs...)
+}
+
+// FlushMessages sends the message updates and replies still held back by
+// WithCoalescing and waits until they are sent. It returns the errors of
+// batched sends not returned to a caller yet, and is a no-op without
+// coalescing.
+func (u *UseCase) FlushMessages() error {
+	if u.coalescer == nil {
+		return nil
+	}
+	return u.coalescer.flushAll()
+}
+
+// sendNow returns the repository to use for a send whose result is needed
+// right away, e.g. the reply timestamp. With coalescing it first sends what
+// is pending for the parent message, so the send keeps its place in the thread.
+func (u *UseCase) sendNow(channel, messageTimestamp string) slackRepository {
+	if u.coalescer == nil {
+		return u.slackRepo
+	}
+	if err := u.coalescer.flush(channel, messageTimestamp); err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", channel, err)
+	}
+	return u.coalescer.slackRepository
+}
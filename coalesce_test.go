@@ -0,0 +1,201 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingSlack holds UpdateMessage calls until released.
+type blockingSlack struct {
+	*MemoryRepository
+	started chan string
+	release chan struct{}
+}
+
+func (b *blockingSlack) UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	b.started <- message
+	<-b.release
+	return b.MemoryRepository.UpdateMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+}
+
+// waitSent waits until the window of every batch elapsed and the batches are sent.
+func waitSent(t *testing.T, r *coalescingRepository) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		r.mu.Lock()
+		idle := len(r.pending) == 0 && len(r.sending) == 0
+		r.mu.Unlock()
+		if idle {
+			return
+		}
+	}
+	t.Fatal("batches not sent after their window")
+}
+
+func TestCoalescingWindow(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	r := newCoalescingRepository(repo, 100*time.Millisecond)
+
+	r.UpdateMessage(ctx, "C1", "first", "danger", "1.0001", "newrelic", "")
+	r.ReplyMessageInThread(ctx, "C1", "acknowledged", "good", "1.0001", "")
+	r.UpdateMessage(ctx, "C1", "second", "good", "1.0001", "newrelic", "")
+	r.ReplyMessageInThread(ctx, "C1", "closed", "good", "1.0001", "")
+	r.UpdateMessage(ctx, "C2", "other", "danger", "1.0001", "newrelic", "")
+	if calls := repo.Calls(); len(calls) != 0 {
+		t.Fatalf("sent before the window elapsed: %+v", calls)
+	}
+
+	if err := r.flushAll(); err != nil {
+		t.Fatalf("flushAll: %v", err)
+	}
+	// The windows elapse without sending the flushed batches again
+	time.Sleep(200 * time.Millisecond)
+
+	updates := repo.Calls("UpdateMessage")
+	if len(updates) != 2 {
+		t.Fatalf("UpdateMessage calls = %+v, want one per parent message", updates)
+	}
+	for _, update := range updates {
+		if update.Channel == "C1" && (update.Text != "second" || update.Color != "good") {
+			t.Errorf("C1 update = %+v, want the last one", update)
+		}
+	}
+	replies := repo.Calls("ReplyMessageInThread")
+	if len(replies) != 1 || replies[0].Text != "acknowledged\n\nclosed" {
+		t.Errorf("ReplyMessageInThread calls = %+v, want the replies joined in order", replies)
+	}
+}
+
+func TestCoalescingSplitsRepliesByColor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	r := newCoalescingRepository(repo, time.Hour)
+
+	r.ReplyMessageInThread(ctx, "C1", "open", "danger", "1.0001", "")
+	r.ReplyMessageInThread(ctx, "C1", "still open", "danger", "1.0001", "")
+	r.ReplyMessageInThread(ctx, "C1", "closed", "good", "1.0001", "")
+	if err := r.flushAll(); err != nil {
+		t.Fatalf("flushAll: %v", err)
+	}
+
+	replies := repo.Calls("ReplyMessageInThread")
+	if len(replies) != 2 || replies[0].Text != "open\n\nstill open" || replies[0].Color != "danger" || replies[1].Text != "closed" || replies[1].Color != "good" {
+		t.Errorf("ReplyMessageInThread calls = %+v, want one reply per color in order", replies)
+	}
+}
+
+func TestCoalescingSendsAfterWindow(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	r := newCoalescingRepository(repo, 10*time.Millisecond)
+
+	r.UpdateMessage(ctx, "C1", "first", "danger", "1.0001", "newrelic", "")
+	waitSent(t, r)
+	if n := len(repo.Calls("UpdateMessage")); n != 1 {
+		t.Fatalf("UpdateMessage calls = %d after the window, want 1", n)
+	}
+
+	// A new window starts after the batch is sent
+	r.UpdateMessage(ctx, "C1", "second", "good", "1.0001", "newrelic", "")
+	if err := r.flush("C1", "1.0001"); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if updates := repo.Calls("UpdateMessage"); len(updates) != 2 || updates[1].Text != "second" {
+		t.Errorf("UpdateMessage calls = %+v", updates)
+	}
+}
+
+func TestCoalescingKeepsOrderAndFlushWaits(t *testing.T) {
+	ctx := context.Background()
+	slack := &blockingSlack{MemoryRepository: NewMemoryRepository(), started: make(chan string, 2), release: make(chan struct{})}
+	r := newCoalescingRepository(slack, 10*time.Millisecond)
+
+	r.UpdateMessage(ctx, "C1", "first", "danger", "1.0001", "newrelic", "")
+	if got := <-slack.started; got != "first" {
+		t.Fatalf("first send = %q", got)
+	}
+
+	// The first batch is still being sent when the second one is flushed
+	r.UpdateMessage(ctx, "C1", "second", "good", "1.0001", "newrelic", "")
+	flushed := make(chan error)
+	go func() {
+		flushed <- r.flush("C1", "1.0001")
+	}()
+
+	select {
+	case got := <-slack.started:
+		t.Fatalf("%q sent while the first batch was in flight", got)
+	case err := <-flushed:
+		t.Fatalf("flush returned %v while the first batch was in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(slack.release)
+	if err := <-flushed; err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	updates := slack.Calls("UpdateMessage")
+	if len(updates) != 2 || updates[0].Text != "first" || updates[1].Text != "second" {
+		t.Errorf("UpdateMessage calls = %+v, want first then second", updates)
+	}
+}
+
+func TestCoalescingSurfacesErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	r := newCoalescingRepository(repo, 10*time.Millisecond)
+
+	repo.Fail("UpdateMessage", errSlackDown)
+	r.UpdateMessage(ctx, "C1", "first", "danger", "1.0001", "newrelic", "")
+	waitSent(t, r)
+	repo.Fail("UpdateMessage", nil)
+
+	// The next call for the message reports the failed batch once
+	if err := r.flush("C1", "1.0001"); !errors.Is(err, errSlackDown) {
+		t.Fatalf("flush = %v, want %v", err, errSlackDown)
+	}
+	if _, _, err := r.UpdateMessage(ctx, "C1", "second", "good", "1.0001", "newrelic", ""); err != nil {
+		t.Errorf("UpdateMessage after the error was reported = %v", err)
+	}
+
+	repo.Fail("ReplyMessageInThread", errSlackDown)
+	r.ReplyMessageInThread(ctx, "C1", "closed", "good", "1.0001", "")
+	if err := r.flushAll(); !errors.Is(err, errSlackDown) {
+		t.Errorf("flushAll = %v, want %v", err, errSlackDown)
+	}
+}
+
+func TestFlushMessages(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t, WithCoalescing(time.Hour))
+
+	if _, err := u.ProcessIncident(ctx, testPayload("42", "open")); err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	if n := len(repo.Calls("SendMessage")); n != 1 {
+		t.Fatalf("SendMessage calls = %d, want the new incident posted right away", n)
+	}
+	// The incident actions are sent after the coalesced opening reply
+	calls := repo.Calls()
+	if last := calls[len(calls)-1]; last.Method != "ReplyBlocksInThread" || calls[len(calls)-2].Method != "ReplyMessageInThread" {
+		t.Errorf("calls = %+v, want the opening reply flushed before the incident actions", calls)
+	}
+
+	if _, err := u.ProcessIncident(ctx, testPayload("42", "acknowledged")); err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	if n := len(repo.Calls("UpdateMessage")); n != 0 {
+		t.Fatalf("UpdateMessage sent within the window")
+	}
+
+	if err := u.FlushMessages(); err != nil {
+		t.Fatalf("FlushMessages: %v", err)
+	}
+	if n := len(repo.Calls("UpdateMessage")); n != 1 {
+		t.Errorf("UpdateMessage calls after FlushMessages = %d, want 1", n)
+	}
+}
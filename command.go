@@ -0,0 +1,151 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// RunCommand executes a diary maintenance subcommand, e.g.
+//
+//	diary postmortem -incident 1234 -channel C01ABCDEF
+func (u *UseCase) RunCommand(ctx context.Context, args []string, stdout io.Writer) (err error) {
+	// Send the Slack messages still held back by WithCoalescing before exiting
+	defer func() {
+		err = errors.Join(err, u.FlushMessages())
+	}()
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand, available: postmortem, export, archive, restore")
+	}
+
+	switch args[0] {
+	case "postmortem":
+		return u.runPostmortem(ctx, args[1:], stdout)
+	case "export":
+		return u.runExport(ctx, args[1:], stdout)
+	case "archive":
+		return u.runArchive(ctx, args[1:], stdout)
+	case "restore":
+		return u.runRestore(ctx, args[1:], stdout)
+	}
+
+	return fmt.Errorf("unknown subcommand %q", args[0])
+}
+
+func (u *UseCase) runPostmortem(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("postmortem", flag.ContinueOnError)
+	incidentID := fs.String("incident", "", "incident ID")
+	channel := fs.String("channel", "", "Slack channel the incident was posted to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *incidentID == "" || *channel == "" {
+		return fmt.Errorf("postmortem requires -incident and -channel")
+	}
+
+	doc, err := u.GeneratePostmortem(ctx, *incidentID, *channel)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(stdout, doc)
+	return err
+}
+
+func (u *UseCase) runExport(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", ExportCSV, "output format: csv, jsonl or parquet")
+	from := fs.String("from", "", "start of the time range, RFC3339 or YYYY-MM-DD (inclusive)")
+	to := fs.String("to", "", "end of the time range, RFC3339 or YYYY-MM-DD (exclusive)")
+	out := fs.String("out", "", "output file, defaults to stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fromTime, err := parseTimeFlag(*from)
+	if err != nil {
+		return fmt.Errorf("-from: %w", err)
+	}
+	toTime, err := parseTimeFlag(*to)
+	if err != nil {
+		return fmt.Errorf("-to: %w", err)
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := u.ExportIncidents(ctx, w, *format, fromTime, toTime, nil)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintf(stdout, "exported %d incidents to %s\n", count, *out)
+	}
+
+	return nil
+}
+
+func (u *UseCase) runArchive(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	days := fs.Int("days", 90, "archive closed incidents that started more than this many days ago")
+	dir := fs.String("dir", "", "archive directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return fmt.Errorf("archive requires -dir")
+	}
+
+	count, err := u.ArchiveIncidents(ctx, RetentionPolicy{Days: *days, Dir: *dir}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "archived %d incidents to %s\n", count, *dir)
+	return nil
+}
+
+func (u *UseCase) runRestore(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	incidentID := fs.String("incident", "", "incident ID")
+	dir := fs.String("dir", "", "archive directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *incidentID == "" || *dir == "" {
+		return fmt.Errorf("restore requires -incident and -dir")
+	}
+
+	incidents, err := u.RestoreIncident(ctx, *dir, *incidentID)
+	if err != nil {
+		return err
+	}
+
+	for _, incident := range incidents {
+		fmt.Fprintf(stdout, "restored incident %s in channel %s\n", incident.IncidentID, incident.Channel)
+	}
+	return nil
+}
+
+func parseTimeFlag(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
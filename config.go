@@ -0,0 +1,312 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/pkg/webhook"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// AlertCondition is a NewRelic alert condition configured for the diary.
+type AlertCondition struct {
+	ID          int          `json:"alert_condition_id"`
+	Name        string       `json:"alert_condition_name"`
+	Causes      []string     `json:"alert_condition_cause"`
+	Runbooks    []Runbook    `json:"runbooks,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Config is the webhook configuration used by UseCase. The file format
+// mirrors the webhook package configuration:
+//
+//	{"slack": {"newrelic": [{"alert_condition_id": 1, "alert_condition_name": "...", "alert_condition_cause": ["..."]}]}}
+//
+// Conditions may also list "runbooks" ({"title", "url"}) and "diagnostics"
+// ({"name", "command"} or {"name", "url", "expect_status"}), which only the
+// file format carries. So do the top-level "severity_rules" and
+// "notification_policies", e.g.
+//
+//	{"severity_rules": [{"vendor": "datadog", "priority": "P2", "severity": "SEV1"}],
+//	 "notification_policies": [{"channel": "*", "timezone": "Asia/Jakarta",
+//	   "business_start": "09:00", "business_end": "18:00",
+//	   "holidays_file": "holidays.txt", "after_hours": "SEV2", "holidays": "SEV2"}]}
+type Config struct {
+	Slack struct {
+		NewRelic []AlertCondition `json:"newrelic"`
+	} `json:"slack"`
+	SeverityRules        []SeverityRule       `json:"severity_rules,omitempty"`
+	NotificationPolicies []NotificationPolicy `json:"notification_policies,omitempty"`
+
+	catalog *ConditionCatalog
+}
+
+// ConfigFromWebhook converts the package-level webhook.DiaryWebhookConfig.
+func ConfigFromWebhook() Config {
+	cfg := Config{}
+	for _, v := range webhook.DiaryWebhookConfig.Slack.NewRelic {
+		cfg.Slack.NewRelic = append(cfg.Slack.NewRelic, AlertCondition{
+			ID:     v.AlertConditionID,
+			Name:   v.AlertConditionName,
+			Causes: v.AlertConditionCause,
+		})
+	}
+
+	return cfg
+}
+
+// Validate checks that every alert condition, severity rule and notification
+// policy is usable, loads holiday calendars and indexes the conditions into
+// the condition catalog. The catalog is built even when validation fails, so
+// a config kept despite errors can still be served. Validate must run before
+// the config is shared between goroutines.
+func (c *Config) Validate() error {
+	catalog, catalogErr := NewConditionCatalog(c.Slack.NewRelic)
+	c.catalog = catalog
+
+	for i, v := range c.Slack.NewRelic {
+		if v.ID <= 0 {
+			return fmt.Errorf("newrelic condition #%d: alert_condition_id must be positive", i)
+		}
+		if v.Name == "" {
+			return fmt.Errorf("newrelic condition %d: alert_condition_name is required", v.ID)
+		}
+		for _, runbook := range v.Runbooks {
+			if err := runbook.validate(); err != nil {
+				return fmt.Errorf("newrelic condition %d: %w", v.ID, err)
+			}
+		}
+		for _, diagnostic := range v.Diagnostics {
+			if err := diagnostic.validate(); err != nil {
+				return fmt.Errorf("newrelic condition %d: %w", v.ID, err)
+			}
+		}
+	}
+	for _, rule := range c.SeverityRules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.NotificationPolicies {
+		if err := c.NotificationPolicies[i].validate(); err != nil {
+			return err
+		}
+	}
+
+	return catalogErr
+}
+
+// emptyCatalog is served by configs that were never validated.
+var emptyCatalog, _ = NewConditionCatalog(nil)
+
+// Catalog returns the condition index built by Validate, or an empty one when
+// the config was not validated. It never modifies the config, so it is safe
+// for concurrent use.
+func (c *Config) Catalog() *ConditionCatalog {
+	if c.catalog == nil {
+		return emptyCatalog
+	}
+
+	return c.catalog
+}
+
+// ConditionCatalog indexes alert conditions by ID and by name.
+type ConditionCatalog struct {
+	conditions []AlertCondition
+	byID       map[int]AlertCondition
+	byName     map[string][]AlertCondition
+}
+
+// NewConditionCatalog indexes conditions. On duplicate IDs the first entry is
+// kept and an error listing the duplicates is returned with the catalog.
+func NewConditionCatalog(conditions []AlertCondition) (*ConditionCatalog, error) {
+	c := &ConditionCatalog{
+		byID:   map[int]AlertCondition{},
+		byName: map[string][]AlertCondition{},
+	}
+
+	duplicates := []int{}
+	for _, v := range conditions {
+		if _, ok := c.byID[v.ID]; ok {
+			duplicates = append(duplicates, v.ID)
+			continue
+		}
+
+		c.conditions = append(c.conditions, v)
+		c.byID[v.ID] = v
+		name := strings.ToLower(v.Name)
+		c.byName[name] = append(c.byName[name], v)
+	}
+	sort.Slice(c.conditions, func(i, j int) bool {
+		return c.conditions[i].ID < c.conditions[j].ID
+	})
+
+	if len(duplicates) > 0 {
+		return c, fmt.Errorf("duplicate alert_condition_id: %v", duplicates)
+	}
+
+	return c, nil
+}
+
+// ByID returns the condition with the given ID.
+func (c *ConditionCatalog) ByID(id int) (AlertCondition, bool) {
+	v, ok := c.byID[id]
+	return v, ok
+}
+
+// ByName returns the conditions with the given name, ignoring case.
+func (c *ConditionCatalog) ByName(name string) []AlertCondition {
+	return c.byName[strings.ToLower(name)]
+}
+
+// List returns all conditions ordered by ID.
+func (c *ConditionCatalog) List() []AlertCondition {
+	conditions := make([]AlertCondition, len(c.conditions))
+	copy(conditions, c.conditions)
+	return conditions
+}
+
+// LoadConfigFile reads and validates a webhook configuration file.
+func LoadConfigFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validate %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// ConfigStore holds the active configuration. Readers always see a complete
+// configuration; reloads swap it atomically.
+type ConfigStore struct {
+	current atomic.Pointer[Config]
+}
+
+// NewConfigStore validates cfg and returns a store serving it.
+func NewConfigStore(cfg Config) (*ConfigStore, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &ConfigStore{}
+	s.current.Store(&cfg)
+	return s, nil
+}
+
+// WithConfig injects the configuration store instead of webhook.DiaryWebhookConfig.
+func WithConfig(store *ConfigStore) Option {
+	return func(u *UseCase) {
+		u.config = store
+	}
+}
+
+// Load returns the active configuration.
+func (s *ConfigStore) Load() *Config {
+	return s.current.Load()
+}
+
+// Reload replaces the active configuration with the content of path.
+// The previous configuration stays active when the file is invalid.
+func (s *ConfigStore) Reload(path string) error {
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	s.current.Store(cfg)
+	return nil
+}
+
+// Watch reloads path whenever its modification time or that of a holidays
+// file it references changes, or the process receives SIGHUP, until ctx is
+// done.
+func (s *ConfigStore) Watch(ctx context.Context, path string, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastMod := s.modTimes(path, nil)
+	reload := func(reason string) {
+		if err := s.Reload(path); err != nil {
+			log.Errorf("Failed reload webhook config %s because: %s", path, err)
+			return
+		}
+		log.Infof("Reloaded webhook config %s (%s)", path, reason)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload("SIGHUP")
+			lastMod = s.modTimes(path, lastMod)
+		case <-ticker.C:
+			mod := s.modTimes(path, lastMod)
+			changed := ""
+			for file, modTime := range mod {
+				if !modTime.Equal(lastMod[file]) {
+					changed = file
+				}
+			}
+			if changed == "" {
+				continue
+			}
+			reload(changed + " changed")
+			// A reload may reference other holidays files
+			lastMod = s.modTimes(path, mod)
+		}
+	}
+}
+
+// modTimes returns the modification times of the config file at path and of
+// the holidays files of the active configuration. Files that cannot be read
+// have the zero time, so deleting one is a change too, and are logged unless
+// last already has them missing.
+func (s *ConfigStore) modTimes(path string, last map[string]time.Time) map[string]time.Time {
+	files := []string{path}
+	for _, policy := range s.Load().NotificationPolicies {
+		if policy.HolidaysFile != "" {
+			files = append(files, policy.HolidaysFile)
+		}
+	}
+
+	modTimes := map[string]time.Time{}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if modTime, seen := last[file]; !seen || !modTime.IsZero() {
+				log.Errorf("Failed stat webhook config %s because: %s", file, err)
+			}
+			modTimes[file] = time.Time{}
+			continue
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	return modTimes
+}
+
+// ListConditions returns every configured alert condition ordered by ID.
+func (u *UseCase) ListConditions() []AlertCondition {
+	return u.config.Load().Catalog().List()
+}
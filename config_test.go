@@ -0,0 +1,68 @@
+package slack
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCatalogIsBuiltByValidate(t *testing.T) {
+	cfg := Config{}
+	cfg.Slack.NewRelic = []AlertCondition{{ID: 7, Name: "CPU high"}, {ID: 8}}
+
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate accepted a condition without a name")
+	}
+	// An invalid config kept by the webhook fallback still serves its conditions
+	if _, ok := cfg.Catalog().ByID(7); !ok {
+		t.Error("condition 7 missing from the catalog of an invalid config")
+	}
+}
+
+func TestCatalogIsReadOnly(t *testing.T) {
+	cfg := &Config{}
+	cfg.Slack.NewRelic = []AlertCondition{{ID: 7, Name: "CPU high"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if len(cfg.Catalog().List()) != 0 {
+				t.Error("unvalidated config has a catalog")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cfg.catalog != nil {
+		t.Error("Catalog wrote the config")
+	}
+}
+
+func TestModTimesRecordsMissingFiles(t *testing.T) {
+	store, err := NewConfigStore(Config{})
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("slack: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := store.modTimes(path, nil)
+	if before[path].IsZero() {
+		t.Fatalf("modTimes = %v, want the time of %s", before, path)
+	}
+
+	// Deleting the file is a change from its last time to the zero time
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	after := store.modTimes(path, before)
+	modTime, ok := after[path]
+	if !ok || !modTime.IsZero() {
+		t.Errorf("modTimes after delete = %v, want %s recorded as missing", after, path)
+	}
+}
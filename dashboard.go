@@ -0,0 +1,149 @@
+package slack
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tokopedia/tdk/go/log"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+const (
+	dashboardRecentResolutions = 20
+	dashboardKeepAlive         = 30 * time.Second
+)
+
+// eventBroker fans incident events out to dashboard subscribers. Slow
+// subscribers miss events instead of blocking ProcessIncident.
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan IncidentEvent]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subscribers: map[chan IncidentEvent]struct{}{},
+	}
+}
+
+func (b *eventBroker) subscribe() chan IncidentEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan IncidentEvent, 16)
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan IncidentEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, ch)
+}
+
+func (b *eventBroker) publish(event IncidentEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// DashboardState is the snapshot rendered by the dashboard.
+type DashboardState struct {
+	Open        []IncidentView `json:"open"`
+	Resolutions []IncidentView `json:"resolutions"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// GetDashboardState returns currently open incidents, oldest first, and the latest resolutions.
+func (u *UseCase) GetDashboardState(ctx context.Context) (DashboardState, error) {
+	state := DashboardState{
+		Open:        []IncidentView{},
+		Resolutions: []IncidentView{},
+		GeneratedAt: time.Now(),
+	}
+
+	open, _, err := u.SearchIncidents(ctx, IncidentQuery{Status: []string{"open", "acknowledged"}, Limit: maxSearchLimit})
+	if err != nil {
+		return state, err
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		state.Open = append(state.Open, u.incidentView(ctx, open[i]))
+	}
+
+	closed, _, err := u.SearchIncidents(ctx, IncidentQuery{Status: []string{"closed"}, Limit: dashboardRecentResolutions})
+	if err != nil {
+		return state, err
+	}
+	for _, incident := range closed {
+		state.Resolutions = append(state.Resolutions, u.incidentView(ctx, incident))
+	}
+
+	return state, nil
+}
+
+func (u *UseCase) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+func (u *UseCase) handleDashboardState(w http.ResponseWriter, r *http.Request) {
+	state, err := u.GetDashboardState(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+// handleDashboardEvents streams incident events as server-sent events.
+func (u *UseCase) handleDashboardEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	events := u.broker.subscribe()
+	defer u.broker.unsubscribe(events)
+
+	keepAlive := time.NewTicker(dashboardKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			event.Payload = nil
+			b, err := json.Marshal(event)
+			if err != nil {
+				log.Errorf("Failed encode incident event because: %s", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: incident\ndata: %s\n\n", b)
+		}
+		flusher.Flush()
+	}
+}
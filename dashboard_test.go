@@ -0,0 +1,117 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardHandlers(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	u.ProcessIncident(ctx, testPayload("2", "open"))
+	u.ProcessIncident(ctx, testPayload("2", "closed"))
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || rec.Body.Len() == 0 {
+		t.Errorf("dashboard = %d %q, %d bytes", rec.Code, rec.Header().Get("Content-Type"), rec.Body.Len())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/state", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dashboard state = %d %s", rec.Code, rec.Body)
+	}
+	var state DashboardState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("decode dashboard state: %v", err)
+	}
+	if len(state.Open) != 1 || state.Open[0].IncidentID != "1" {
+		t.Errorf("open incidents = %+v, want incident 1", state.Open)
+	}
+	if len(state.Resolutions) != 1 || state.Resolutions[0].IncidentID != "2" {
+		t.Errorf("resolutions = %+v, want incident 2", state.Resolutions)
+	}
+}
+
+// subscribers returns the number of dashboard event subscribers of u.
+func subscribers(u *UseCase) int {
+	u.broker.mu.Lock()
+	defer u.broker.mu.Unlock()
+
+	return len(u.broker.subscribers)
+}
+
+// waitSubscribers waits until u has n dashboard event subscribers.
+func waitSubscribers(t *testing.T, u *UseCase, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if subscribers(u) == n {
+			return
+		}
+	}
+	t.Fatalf("%d dashboard subscribers, want %d", subscribers(u), n)
+}
+
+func TestDashboardEventStream(t *testing.T) {
+	u, _ := newTestUseCase(t)
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/dashboard/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /dashboard/events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("event stream = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	waitSubscribers(t, u, 1)
+
+	u.ProcessIncident(context.Background(), testPayload("42", "open"))
+
+	// The first event of the incident is its delivery
+	stream := bufio.NewScanner(resp.Body)
+	var name string
+	for stream.Scan() {
+		line := stream.Text()
+		if strings.HasPrefix(line, "event: ") {
+			name = strings.TrimPrefix(line, "event: ")
+			continue
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var event IncidentEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatalf("decode event %q: %v", line, err)
+		}
+		if name != "incident" || event.IncidentID != "42" || event.Type != EventReceived || event.Payload != nil {
+			t.Errorf("event %s = %+v", name, event)
+		}
+		break
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("read event stream: %v", err)
+	}
+
+	// Disconnecting unsubscribes
+	cancel()
+	waitSubscribers(t, u, 0)
+}
@@ -0,0 +1,248 @@
+package slack
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+// Supported export formats.
+const (
+	ExportCSV     = "csv"
+	ExportJSONL   = "jsonl"
+	ExportParquet = "parquet"
+)
+
+// exportPageSize is how many incidents are read from the repository per batch.
+const exportPageSize = 200
+
+// ExportRow is one exported incident. Labels are kept as their JSON document.
+type ExportRow struct {
+	IncidentID  string `json:"incident_id" parquet:"incident_id"`
+	ConditionID int64  `json:"condition_id" parquet:"condition_id"`
+	Name        string `json:"name" parquet:"name"`
+	URL         string `json:"url" parquet:"url"`
+	Description string `json:"description" parquet:"description"`
+	Owner       string `json:"owner" parquet:"owner"`
+	Vendor      string `json:"vendor" parquet:"vendor"`
+	Status      string `json:"status" parquet:"status"`
+	Severity    string `json:"severity" parquet:"severity"`
+	RootCause   string `json:"root_cause" parquet:"root_cause"`
+	Channel     string `json:"channel" parquet:"channel"`
+	Labels      string `json:"labels" parquet:"labels"`
+	UserACK     string `json:"user_ack" parquet:"user_ack"`
+	UserACKName string `json:"user_ack_name" parquet:"user_ack_name"`
+	MessageTs   string `json:"message_ts" parquet:"message_ts"`
+	StartTime   int64  `json:"start_time" parquet:"start_time,timestamp(millisecond)"`
+	RecoverTime int64  `json:"recover_time,omitempty" parquet:"recover_time,optional,timestamp(millisecond)"`
+	TTRSeconds  int64  `json:"ttr_seconds,omitempty" parquet:"ttr_seconds,optional"`
+}
+
+var exportHeader = []string{
+	"incident_id", "condition_id", "name", "url", "description", "owner", "vendor", "status",
+	"severity", "root_cause", "channel", "labels", "user_ack", "user_ack_name", "message_ts", "start_time",
+	"recover_time", "ttr_seconds",
+}
+
+func newExportRow(i entitySlack.Incident) ExportRow {
+	row := ExportRow{
+		IncidentID:  i.IncidentID,
+		ConditionID: int64(i.ConditionID),
+		Name:        i.Name,
+		URL:         i.URL,
+		Description: i.Description,
+		Owner:       i.Owner,
+		Vendor:      i.GeneratedBy,
+		Status:      i.Status,
+		Severity:    i.Severity,
+		RootCause:   i.RootCause,
+		Channel:     i.Channel,
+		Labels:      i.Labels,
+		UserACK:     i.UserACK,
+		MessageTs:   i.MessageTimestamp,
+		StartTime:   i.StartTime.UnixMilli(),
+	}
+	if row.RootCause == "null" {
+		row.RootCause = ""
+	}
+	if !i.RecoverTime.IsZero() {
+		row.RecoverTime = i.RecoverTime.UnixMilli()
+		row.TTRSeconds = int64(i.RecoverTime.Sub(i.StartTime).Seconds())
+	}
+
+	return row
+}
+
+// exportRow is newExportRow with the acknowledging user resolved to a display name.
+func (u *UseCase) exportRow(ctx context.Context, i entitySlack.Incident) ExportRow {
+	row := newExportRow(i)
+	if row.UserACK != "" {
+		row.UserACKName = u.displayName(ctx, row.UserACK)
+	}
+
+	return row
+}
+
+func (r ExportRow) record() []string {
+	recoverTime := ""
+	if r.RecoverTime > 0 {
+		recoverTime = time.UnixMilli(r.RecoverTime).UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		r.IncidentID, strconv.FormatInt(r.ConditionID, 10), r.Name, r.URL, r.Description, r.Owner, r.Vendor, r.Status,
+		r.Severity, r.RootCause, r.Channel, r.Labels, r.UserACK, r.UserACKName, r.MessageTs,
+		time.UnixMilli(r.StartTime).UTC().Format(time.RFC3339), recoverTime, strconv.FormatInt(r.TTRSeconds, 10),
+	}
+}
+
+// exportWriter writes rows in one of the export formats.
+type exportWriter interface {
+	Write(row ExportRow) error
+	Flush() error
+	Close() error
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportHeader); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{w: cw}, nil
+	case ExportJSONL:
+		return &jsonlExportWriter{enc: json.NewEncoder(w)}, nil
+	case ExportParquet:
+		return &parquetExportWriter{w: parquet.NewGenericWriter[ExportRow](w)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported export format %q, use csv, jsonl or parquet", format)
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvExportWriter) Write(row ExportRow) error {
+	return c.w.Write(row.record())
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	return c.Flush()
+}
+
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlExportWriter) Write(row ExportRow) error {
+	return j.enc.Encode(row)
+}
+
+func (j *jsonlExportWriter) Flush() error {
+	return nil
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return nil
+}
+
+type parquetExportWriter struct {
+	w *parquet.GenericWriter[ExportRow]
+}
+
+func (p *parquetExportWriter) Write(row ExportRow) error {
+	_, err := p.w.Write([]ExportRow{row})
+	return err
+}
+
+// Flush ends the current row group so it can be streamed out.
+func (p *parquetExportWriter) Flush() error {
+	return p.w.Flush()
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.w.Close()
+}
+
+// ExportIncidents streams every incident started in [from, to) to w in the
+// given format. Incidents are read and written one page at a time; flush, if
+// not nil, is called after each page.
+func (u *UseCase) ExportIncidents(ctx context.Context, w io.Writer, format string, from, to time.Time, flush func()) (int, error) {
+	export, err := u.startExport(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return export.write(ctx, w, format, flush)
+}
+
+// incidentExport is an export whose first page has already been read, so
+// repository failures surface before anything is written.
+type incidentExport struct {
+	u         *UseCase
+	query     IncidentQuery
+	incidents []entitySlack.Incident
+	total     int
+}
+
+// startExport reads the first page of incidents started in [from, to).
+func (u *UseCase) startExport(ctx context.Context, from, to time.Time) (*incidentExport, error) {
+	query := IncidentQuery{From: from, To: to, Limit: exportPageSize}
+	incidents, total, err := u.SearchIncidents(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &incidentExport{u: u, query: query, incidents: incidents, total: total}, nil
+}
+
+// write writes the first page and reads and writes the remaining ones.
+func (e *incidentExport) write(ctx context.Context, w io.Writer, format string, flush func()) (int, error) {
+	out, err := newExportWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	incidents, total := e.incidents, e.total
+	for {
+		for _, incident := range incidents {
+			if err := out.Write(e.u.exportRow(ctx, incident)); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := out.Flush(); err != nil {
+			return count, err
+		}
+		if flush != nil {
+			flush()
+		}
+
+		e.query.Offset += len(incidents)
+		if len(incidents) == 0 || e.query.Offset >= total {
+			break
+		}
+
+		incidents, total, err = e.u.SearchIncidents(ctx, e.query)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, out.Close()
+}
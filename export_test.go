@@ -0,0 +1,105 @@
+package slack
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestExportAPIReportsRepositoryFailure(t *testing.T) {
+	u, repo := newTestUseCase(t)
+	u.ProcessIncident(context.Background(), testPayload("42", "open"))
+
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	repo.Fail("SearchIncidents", errSlackDown)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/incidents/export?format=csv", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("export with failing repository = %d, want 500", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != "" {
+		t.Errorf("failed export sent Content-Disposition %q", got)
+	}
+
+	repo.Fail("SearchIncidents", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/incidents/export?format=csv", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("export = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 2 || records[1][0] != "42" {
+		t.Errorf("exported records = %q, %v", records, err)
+	}
+}
+
+func TestAcknowledgingUserIsResolved(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	alice := slack.User{ID: "U0ALICE01", Name: "alice"}
+	alice.Profile.DisplayName = "Alice"
+	repo.AddUser(alice)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+	u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01"))
+
+	state, err := u.GetDashboardState(ctx)
+	if err != nil || len(state.Open) != 1 {
+		t.Fatalf("GetDashboardState = %+v, %v", state, err)
+	}
+	if view := state.Open[0]; view.UserACK != "U0ALICE01" || view.UserACKName != "Alice" {
+		t.Errorf("dashboard view user = %q (%q), want U0ALICE01 (Alice)", view.UserACK, view.UserACKName)
+	}
+
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/incidents/export?format=jsonl", nil))
+	row := ExportRow{}
+	if err := json.NewDecoder(rec.Body).Decode(&row); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if row.UserACK != "U0ALICE01" || row.UserACKName != "Alice" {
+		t.Errorf("exported user = %q (%q), want U0ALICE01 (Alice)", row.UserACK, row.UserACKName)
+	}
+}
+
+func TestSearchAPISeverity(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+	critical := testPayload("1", "open")
+	u.ProcessIncident(ctx, critical)
+	high := testPayload("2", "open")
+	high.Severity = "P2"
+	u.ProcessIncident(ctx, high)
+
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	for target, want := range map[string]int{
+		"/incidents?severity=critical": 1,
+		"/incidents?severity=SEV1":     1,
+		"/incidents?severity=high":     1,
+		"/incidents?severity=sev4":     0,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		body := struct{ Total int }{}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusOK || body.Total != want {
+			t.Errorf("GET %s = %d, total %d, %v; want %d", target, rec.Code, body.Total, err, want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/incidents?severity=urgent", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET unknown severity = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,210 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// FakeSlackCall is one recorded call to the Slack API fake.
+type FakeSlackCall struct {
+	Method          string
+	Channel         string
+	Timestamp       string
+	ThreadTimestamp string
+	Text            string
+	Color           string
+	Value           string
+	Username        string
+	Options         []string
+	Blocks          []slack.Block
+}
+
+// FakeSlack implements the Slack API half of slackRepository in memory.
+// Every call is recorded, posted messages get increasing timestamps, and
+// any method can be made to fail with Fail.
+type FakeSlack struct {
+	mu       sync.Mutex
+	seq      int
+	calls    []FakeSlackCall
+	channels map[string]string
+	replies  map[string][]slack.Message
+	failures map[string]error
+	users    map[string]slack.User
+}
+
+func NewFakeSlack() *FakeSlack {
+	return &FakeSlack{
+		channels: map[string]string{},
+		replies:  map[string][]slack.Message{},
+		failures: map[string]error{},
+		users:    map[string]slack.User{},
+	}
+}
+
+// Fail makes every following call to method return err. A nil err clears the failure.
+func (f *FakeSlack) Fail(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.failures, method)
+		return
+	}
+	f.failures[method] = err
+}
+
+// Calls returns the recorded calls, optionally filtered by method.
+func (f *FakeSlack) Calls(method ...string) []FakeSlackCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := []FakeSlackCall{}
+	for _, call := range f.calls {
+		if len(method) == 0 || call.Method == method[0] {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ChannelOf returns the channel a fake message timestamp was posted to.
+func (f *FakeSlack) ChannelOf(messageTimestamp string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.channels[messageTimestamp]
+}
+
+func (f *FakeSlack) record(call FakeSlackCall) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failures[call.Method]; err != nil {
+		return err
+	}
+	f.calls = append(f.calls, call)
+	return nil
+}
+
+func (f *FakeSlack) nextTimestamp(channel string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	ts := fmt.Sprintf("1700000000.%06d", f.seq)
+	f.channels[ts] = channel
+	return ts
+}
+
+func (f *FakeSlack) SendMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	if err := f.record(FakeSlackCall{Method: "SendMessage", Channel: channel, Text: message, Color: color}); err != nil {
+		return "", "", err
+	}
+
+	return channel, f.nextTimestamp(channel), nil
+}
+
+func (f *FakeSlack) UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	if err := f.record(FakeSlackCall{Method: "UpdateMessage", Channel: channel, Timestamp: messageTimestamp, Text: message, Color: color}); err != nil {
+		return "", "", err
+	}
+
+	return channel, messageTimestamp, nil
+}
+
+func (f *FakeSlack) ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error) {
+	if err := f.record(FakeSlackCall{Method: "ReplyMessageInThread", Channel: channel, ThreadTimestamp: messageTimestamp, Text: message, Color: color}); err != nil {
+		return "", "", err
+	}
+
+	ts := f.nextTimestamp(channel)
+
+	f.mu.Lock()
+	f.replies[messageTimestamp] = append(f.replies[messageTimestamp], slack.Message{
+		Msg: slack.Msg{Channel: channel, Timestamp: ts, ThreadTimestamp: messageTimestamp, Text: message},
+	})
+	f.mu.Unlock()
+
+	return channel, ts, nil
+}
+
+func (f *FakeSlack) ReplyBlocksInThread(ctx context.Context, channel, messageTimestamp, fallback string, blocks ...slack.Block) (string, error) {
+	if err := f.record(FakeSlackCall{Method: "ReplyBlocksInThread", Channel: channel, ThreadTimestamp: messageTimestamp, Text: fallback, Blocks: blocks}); err != nil {
+		return "", err
+	}
+
+	ts := f.nextTimestamp(channel)
+
+	f.mu.Lock()
+	f.replies[messageTimestamp] = append(f.replies[messageTimestamp], slack.Message{
+		Msg: slack.Msg{Channel: channel, Timestamp: ts, ThreadTimestamp: messageTimestamp, Text: fallback, Blocks: slack.Blocks{BlockSet: blocks}},
+	})
+	f.mu.Unlock()
+
+	return ts, nil
+}
+
+func (f *FakeSlack) SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	return f.SubmitButtonActionWithForm(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal)
+}
+
+// SubmitButtonActionWithForm records the call like SubmitButtonAction, with
+// the form blocks.
+func (f *FakeSlack) SubmitButtonActionWithForm(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool, formBlocks ...slack.Block) (string, error) {
+	if err := f.record(FakeSlackCall{Method: "SubmitButtonAction", Channel: channel, Timestamp: messageTimestamp, Text: title + message, Color: color, Username: username, Options: options, Blocks: formBlocks}); err != nil {
+		return "", err
+	}
+
+	return "ok", nil
+}
+
+func (f *FakeSlack) ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	if err := f.record(FakeSlackCall{Method: "ReplaceMessage", Channel: channel, Timestamp: messageTimestamp, Text: title + message, Color: color, Value: value, Username: username}); err != nil {
+		return "", err
+	}
+
+	return "ok", nil
+}
+
+func (f *FakeSlack) GetThreadReplies(ctx context.Context, channel, messageTimestamp string) ([]slack.Message, error) {
+	if err := f.record(FakeSlackCall{Method: "GetThreadReplies", Channel: channel, ThreadTimestamp: messageTimestamp}); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	replies := make([]slack.Message, len(f.replies[messageTimestamp]))
+	copy(replies, f.replies[messageTimestamp])
+	return replies, nil
+}
+
+func (f *FakeSlack) OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error {
+	return f.record(FakeSlackCall{Method: "OpenView", Text: view.CallbackID, Value: triggerID, Blocks: view.Blocks.BlockSet})
+}
+
+// AddUser makes user known to GetUserInfo.
+func (f *FakeSlack) AddUser(user slack.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.users[user.ID] = user
+}
+
+func (f *FakeSlack) GetUserInfo(ctx context.Context, userID string) (*slack.User, error) {
+	if err := f.record(FakeSlackCall{Method: "GetUserInfo", Value: userID}); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("users.info %s: user_not_found", userID)
+	}
+	return &user, nil
+}
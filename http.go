@@ -0,0 +1,465 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// ErrUnauthenticated is returned for API requests without an authenticated user.
+var ErrUnauthenticated = errors.New("authentication required")
+
+// Authenticator returns the Slack user ID of the user making r.
+type Authenticator func(r *http.Request) (string, error)
+
+// WithAuthenticator identifies the users of API endpoints that act on their
+// behalf, such as POST /incidents/bulk. Those endpoints reject every request
+// without it.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(u *UseCase) {
+		u.authenticator = authenticator
+	}
+}
+
+// authenticate returns the user making r.
+func (u *UseCase) authenticate(r *http.Request) (string, error) {
+	if u.authenticator == nil {
+		return "", ErrUnauthenticated
+	}
+
+	actor, err := u.authenticator(r)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+	}
+	if actor == "" {
+		return "", ErrUnauthenticated
+	}
+
+	return actor, nil
+}
+
+// RegisterRoutes mounts the diary incident API on mux.
+func (u *UseCase) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /incidents", u.handleSearchIncidents)
+	mux.HandleFunc("GET /incidents/export", u.handleExportIncidents)
+	mux.HandleFunc("GET /incidents/{id}/timeline", u.handleTimeline)
+	mux.HandleFunc("GET /incidents/{id}/links", u.handleGetLinks)
+	mux.HandleFunc("POST /incidents/{id}/links", u.handleCreateLink)
+	mux.HandleFunc("GET /action-items/overdue", u.handleOverdueActionItems)
+	mux.HandleFunc("POST /action-items/{id}/status", u.handleUpdateActionItemStatus)
+	mux.HandleFunc("GET /conditions", u.handleListConditions)
+	mux.HandleFunc("GET /conditions/{id}", u.handleGetCondition)
+	mux.HandleFunc("GET /dashboard", u.handleDashboard)
+	mux.HandleFunc("GET /dashboard/state", u.handleDashboardState)
+	mux.HandleFunc("GET /dashboard/events", u.handleDashboardEvents)
+	mux.HandleFunc("POST /incidents/{id}/customer-facing", u.handleSetCustomerFacing)
+	mux.HandleFunc("GET /incidents/{id}/assignments", u.handleGetAssignments)
+	mux.HandleFunc("POST /incidents/{id}/assignments", u.handleAssign)
+	mux.HandleFunc("GET /users/{id}", u.handleGetUser)
+	mux.HandleFunc("POST /incidents/bulk", u.handleBulk)
+	if u.metrics != nil {
+		mux.Handle("GET /metrics", u.metrics.Handler())
+	}
+}
+
+// handleSearchIncidents serves GET /incidents. Supported parameters are
+// status (comma separated), channel, vendor, severity, owner, root_cause,
+// label (repeated key=value), from and to (RFC3339), page and per_page.
+func (u *UseCase) handleSearchIncidents(w http.ResponseWriter, r *http.Request) {
+	query, page, err := parseIncidentQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	incidents, total, err := u.SearchIncidents(r.Context(), query)
+	if err == ErrSearchUnsupported {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	views := make([]IncidentView, 0, len(incidents))
+	for _, incident := range incidents {
+		views = append(views, u.incidentView(r.Context(), incident))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"incidents": views,
+		"total":     total,
+		"page":      page,
+		"per_page":  query.Limit,
+	})
+}
+
+func parseIncidentQuery(values url.Values) (IncidentQuery, int, error) {
+	query := IncidentQuery{
+		Channel:   values.Get("channel"),
+		Vendor:    values.Get("vendor"),
+		Severity:  values.Get("severity"),
+		Owner:     values.Get("owner"),
+		RootCause: values.Get("root_cause"),
+		Labels:    map[string]string{},
+	}
+
+	if query.Severity != "" {
+		if _, ok := ParseSeverity(query.Severity); !ok {
+			return query, 0, fmt.Errorf("unknown severity %q", query.Severity)
+		}
+	}
+	if status := values.Get("status"); status != "" {
+		query.Status = strings.Split(status, ",")
+	}
+	for _, label := range values["label"] {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 {
+			return query, 0, fmt.Errorf("label %q must be key=value", label)
+		}
+		query.Labels[kv[0]] = kv[1]
+	}
+
+	var err error
+	if from := values.Get("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, 0, fmt.Errorf("from: %w", err)
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, 0, fmt.Errorf("to: %w", err)
+		}
+	}
+
+	page := 1
+	if p := values.Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			return query, 0, fmt.Errorf("page must be a positive integer")
+		}
+	}
+	if perPage := values.Get("per_page"); perPage != "" {
+		if query.Limit, err = strconv.Atoi(perPage); err != nil {
+			return query, 0, fmt.Errorf("per_page must be an integer")
+		}
+	}
+	query = query.Normalized()
+	query.Offset = (page - 1) * query.Limit
+
+	return query, page, nil
+}
+
+// handleExportIncidents streams GET /incidents/export?format=csv|jsonl|parquet&from=&to=.
+func (u *UseCase) handleExportIncidents(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportCSV
+	}
+
+	from, err := parseTimeFlag(r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("from: %w", err))
+		return
+	}
+	to, err := parseTimeFlag(r.URL.Query().Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("to: %w", err))
+		return
+	}
+
+	contentType := map[string]string{
+		ExportCSV:     "text/csv",
+		ExportJSONL:   "application/x-ndjson",
+		ExportParquet: "application/vnd.apache.parquet",
+	}[format]
+	if contentType == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported export format %q", format))
+		return
+	}
+
+	// Read the first page before sending headers, so a failing repository
+	// is reported as an error instead of an empty export
+	export, err := u.startExport(r.Context(), from, to)
+	if err == ErrSearchUnsupported {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=incidents.%s", format))
+
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+
+	// Headers are already sent once rows stream, so later failures can only be logged.
+	if _, err := export.write(r.Context(), w, format, flush); err != nil {
+		log.Errorf("Failed export incidents because: %s", err)
+	}
+}
+
+// handleTimeline serves GET /incidents/{id}/timeline?channel=.
+func (u *UseCase) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	incidentID := r.PathValue("id")
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("channel is required"))
+		return
+	}
+
+	events, err := u.GetIncidentTimeline(r.Context(), incidentID, channel)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(events) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "incident not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"incident_id": incidentID,
+		"channel":     channel,
+		"events":      events,
+	})
+}
+
+func (u *UseCase) handleOverdueActionItems(w http.ResponseWriter, r *http.Request) {
+	report, err := u.OverdueActionItems(r.Context(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"teams": report,
+	})
+}
+
+// handleUpdateActionItemStatus serves POST /action-items/{id}/status with a
+// body of {"status": "done"} on behalf of the authenticated user.
+func (u *UseCase) handleUpdateActionItemStatus(w http.ResponseWriter, r *http.Request) {
+	actor, err := u.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	req := struct {
+		Status string `json:"status"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	item, err := u.UpdateActionItemStatus(r.Context(), r.PathValue("id"), req.Status, actor)
+	if errors.Is(err, ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "action item not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (u *UseCase) handleListConditions(w http.ResponseWriter, r *http.Request) {
+	conditions := u.ListConditions()
+	if name := r.URL.Query().Get("name"); name != "" {
+		conditions = u.config.Load().Catalog().ByName(name)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"conditions": conditions,
+	})
+}
+
+func (u *UseCase) handleGetCondition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	condition, ok := u.config.Load().Catalog().ByID(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "condition not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, condition)
+}
+
+func (u *UseCase) handleGetLinks(w http.ResponseWriter, r *http.Request) {
+	parents, children, err := u.GetIncidentLinks(r.Context(), r.PathValue("id"), r.URL.Query().Get("channel"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"parents":  parents,
+		"children": children,
+	})
+}
+
+// handleCreateLink serves POST /incidents/{id}/links with a body of
+// {"channel": "...", "type": "caused-by", "parent_id": "...", "parent_channel": "..."}
+// on behalf of the authenticated user.
+func (u *UseCase) handleCreateLink(w http.ResponseWriter, r *http.Request) {
+	actor, err := u.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	link := IncidentLink{}
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	link.IncidentID = r.PathValue("id")
+	link.CreatedBy = actor
+
+	link, err = u.LinkIncidents(r.Context(), link)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// handleSetCustomerFacing serves POST /incidents/{id}/customer-facing?channel=...
+// on behalf of the authenticated user, with an optional body of
+// {"summary": "..."} naming the incident on the status page.
+func (u *UseCase) handleSetCustomerFacing(w http.ResponseWriter, r *http.Request) {
+	actor, err := u.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("channel is required"))
+		return
+	}
+
+	req := struct {
+		Summary string `json:"summary"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = u.SetCustomerFacing(r.Context(), r.PathValue("id"), channel, actor, req.Summary)
+	if errors.Is(err, ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "incident not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u *UseCase) handleGetAssignments(w http.ResponseWriter, r *http.Request) {
+	history, err := u.GetAssignments(r.Context(), r.PathValue("id"), r.URL.Query().Get("channel"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// handleAssign serves POST /incidents/{id}/assignments with a body of
+// {"channel": "...", "assignee": "..."} on behalf of the authenticated user.
+func (u *UseCase) handleAssign(w http.ResponseWriter, r *http.Request) {
+	actor, err := u.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	req := Assignment{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	assignment, err := u.AssignIncident(r.Context(), r.PathValue("id"), req.Channel, req.Assignee, actor)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, assignment)
+}
+
+// handleBulk serves POST /incidents/bulk on behalf of the authenticated user.
+func (u *UseCase) handleBulk(w http.ResponseWriter, r *http.Request) {
+	actor, err := u.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	req := BulkRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Actor = actor
+
+	results, err := u.BulkApply(r.Context(), req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (u *UseCase) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := u.ResolveUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Failed write http response because: %s", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
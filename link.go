@@ -0,0 +1,321 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Incident link types. Children linked as caused-by or duplicate-of are
+// resolved together with their parent; related links are informational.
+const (
+	LinkCausedBy    = "caused-by"
+	LinkDuplicateOf = "duplicate-of"
+	LinkRelated     = "related"
+)
+
+// EventLinked is recorded on both incidents when they are linked.
+const EventLinked = "linked"
+
+// Block IDs of the link inputs in the Ack form.
+const (
+	LinkTypeBlock   = "link_type"
+	LinkParentBlock = "link_parent"
+)
+
+// IncidentLink relates a child incident to its parent.
+type IncidentLink struct {
+	IncidentID    string    `json:"incident_id"`
+	Channel       string    `json:"channel"`
+	Type          string    `json:"type"`
+	ParentID      string    `json:"parent_id"`
+	ParentChannel string    `json:"parent_channel"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type linkRepository interface {
+	InsertIncidentLink(ctx context.Context, link IncidentLink) error
+	GetIncidentLinks(ctx context.Context, incidentID, channel string) ([]IncidentLink, error)
+	GetChildLinks(ctx context.Context, parentID, parentChannel string) ([]IncidentLink, error)
+	// DeleteIncidentLinks removes the links an incident is either the child or the parent of.
+	DeleteIncidentLinks(ctx context.Context, incidentID, channel string) error
+}
+
+// WithLinks replaces the default in-memory incident link store, e.g. with a
+// durable repository.SQLRepository.
+func WithLinks(links linkRepository) Option {
+	return func(u *UseCase) {
+		u.links = links
+	}
+}
+
+// MemoryLinks is an in-process linkRepository. Links are lost on restart;
+// use a durable repository.SQLRepository in production.
+type MemoryLinks struct {
+	mu    sync.RWMutex
+	links []IncidentLink
+}
+
+func NewMemoryLinks() *MemoryLinks {
+	return &MemoryLinks{}
+}
+
+func (m *MemoryLinks) InsertIncidentLink(ctx context.Context, link IncidentLink) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, l := range m.links {
+		if l.IncidentID == link.IncidentID && l.Channel == link.Channel && l.ParentID == link.ParentID && l.ParentChannel == link.ParentChannel {
+			m.links[i] = link
+			return nil
+		}
+	}
+	m.links = append(m.links, link)
+	return nil
+}
+
+func (m *MemoryLinks) GetIncidentLinks(ctx context.Context, incidentID, channel string) ([]IncidentLink, error) {
+	return m.filter(func(l IncidentLink) bool { return l.IncidentID == incidentID && l.Channel == channel }), nil
+}
+
+func (m *MemoryLinks) GetChildLinks(ctx context.Context, parentID, parentChannel string) ([]IncidentLink, error) {
+	return m.filter(func(l IncidentLink) bool { return l.ParentID == parentID && l.ParentChannel == parentChannel }), nil
+}
+
+func (m *MemoryLinks) DeleteIncidentLinks(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := []IncidentLink{}
+	for _, l := range m.links {
+		if (l.IncidentID == incidentID && l.Channel == channel) || (l.ParentID == incidentID && l.ParentChannel == channel) {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	m.links = kept
+	return nil
+}
+
+func (m *MemoryLinks) filter(match func(IncidentLink) bool) []IncidentLink {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	links := []IncidentLink{}
+	for _, l := range m.links {
+		if match(l) {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// LinkBlocks returns the optional link inputs appended to the Ack form.
+func LinkBlocks() []slack.Block {
+	options := []*slack.OptionBlockObject{}
+	for _, linkType := range []string{LinkCausedBy, LinkDuplicateOf, LinkRelated} {
+		options = append(options, slack.NewOptionBlockObject(linkType, slack.NewTextBlockObject(slack.PlainTextType, strings.Replace(linkType, "-", " ", -1), false, false), nil))
+	}
+
+	linkType := slack.NewInputBlock(
+		LinkTypeBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Link to another incident", false, false),
+		nil,
+		slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, slack.NewTextBlockObject(slack.PlainTextType, "Relationship", false, false), LinkTypeBlock, options...),
+	)
+	linkType.Optional = true
+
+	parent := slack.NewInputBlock(
+		LinkParentBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Parent incident ID", false, false),
+		nil,
+		slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "e.g. 123456", false, false), LinkParentBlock),
+	)
+	parent.Optional = true
+
+	return []slack.Block{linkType, parent}
+}
+
+// LinkIncidents links a child incident to its parent and cross-references
+// both Slack threads.
+func (u *UseCase) LinkIncidents(ctx context.Context, link IncidentLink) (IncidentLink, error) {
+	switch link.Type {
+	case LinkCausedBy, LinkDuplicateOf, LinkRelated:
+	default:
+		return link, fmt.Errorf("invalid link type %q", link.Type)
+	}
+	if link.ParentChannel == "" {
+		link.ParentChannel = link.Channel
+	}
+	if link.IncidentID == link.ParentID && link.Channel == link.ParentChannel {
+		return link, fmt.Errorf("incident %s cannot be linked to itself", link.IncidentID)
+	}
+
+	child, err := u.slackRepo.GetNewRelicIncidentByID(ctx, link.IncidentID, link.Channel)
+	if err != nil {
+		return link, fmt.Errorf("incident %s: %w", link.IncidentID, err)
+	}
+	parent, err := u.slackRepo.GetNewRelicIncidentByID(ctx, link.ParentID, link.ParentChannel)
+	if err != nil {
+		return link, fmt.Errorf("parent incident %s: %w", link.ParentID, err)
+	}
+	if link.Type != LinkRelated {
+		cyclic, err := u.resolvesWith(ctx, parent, child)
+		if err != nil {
+			return link, err
+		}
+		if cyclic {
+			return link, fmt.Errorf("incident %s already resolves incident %s", child.IncidentID, parent.IncidentID)
+		}
+	}
+
+	link.CreatedAt = time.Now()
+	if err := u.links.InsertIncidentLink(ctx, link); err != nil {
+		return link, err
+	}
+
+	for _, event := range []IncidentEvent{
+		{IncidentID: child.IncidentID, Channel: child.Channel, Type: EventLinked, Actor: link.CreatedBy, Detail: fmt.Sprintf("%s %s", link.Type, parent.IncidentID)},
+		{IncidentID: parent.IncidentID, Channel: parent.Channel, Type: EventLinked, Actor: link.CreatedBy, Detail: fmt.Sprintf("child %s (%s)", child.IncidentID, link.Type)},
+	} {
+		u.recordEvent(ctx, event)
+	}
+
+	childNote := fmt.Sprintf(":link: This incident is *%s* <%s|%s>", strings.Replace(link.Type, "-", " ", -1), threadURL(parent), parent.Name)
+	_, _, err = u.slackRepo.ReplyMessageInThread(ctx, child.Channel, childNote, u.GetColorStr(child.Status, child.Severity), child.MessageTimestamp, child.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", child.Channel, err)
+	}
+
+	parentNote := fmt.Sprintf(":link: Linked child incident <%s|%s> (%s)", threadURL(child), child.Name, link.Type)
+	_, _, err = u.slackRepo.ReplyMessageInThread(ctx, parent.Channel, parentNote, u.GetColorStr(parent.Status, parent.Severity), parent.MessageTimestamp, parent.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", parent.Channel, err)
+	}
+
+	// A child linked to an already resolved parent is resolved right away.
+	if parent.Status == "closed" && child.Status != "closed" && link.Type != LinkRelated {
+		note := fmt.Sprintf(":white_check_mark: Resolved together with parent incident <%s|%s>", threadURL(parent), parent.Name)
+		if _, err := u.applyState(ctx, child, "closed", link.CreatedBy, note); err != nil {
+			log.Errorf("Error store message to database: %s", err)
+		}
+	}
+
+	return link, nil
+}
+
+// GetIncidentLinks returns the parents of an incident and its children.
+func (u *UseCase) GetIncidentLinks(ctx context.Context, incidentID, channel string) ([]IncidentLink, []IncidentLink, error) {
+	parents, err := u.links.GetIncidentLinks(ctx, incidentID, channel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	children, err := u.links.GetChildLinks(ctx, incidentID, channel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parents, children, nil
+}
+
+// resolvesWith reports whether incident is resolved together with ancestor,
+// following caused-by and duplicate-of links up from incident.
+func (u *UseCase) resolvesWith(ctx context.Context, incident, ancestor entitySlack.Incident) (bool, error) {
+	seen := map[incidentKey]bool{}
+	queue := []incidentKey{{incident.IncidentID, incident.Channel}}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		if key == (incidentKey{ancestor.IncidentID, ancestor.Channel}) {
+			return true, nil
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		parents, err := u.links.GetIncidentLinks(ctx, key.incidentID, key.channel)
+		if err != nil {
+			return false, err
+		}
+		for _, link := range parents {
+			if link.Type != LinkRelated {
+				queue = append(queue, incidentKey{link.ParentID, link.ParentChannel})
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// resolveChildren closes every caused-by and duplicate-of child of a resolved parent.
+func (u *UseCase) resolveChildren(ctx context.Context, parent entitySlack.Incident) {
+	children, err := u.links.GetChildLinks(ctx, parent.IncidentID, parent.Channel)
+	if err != nil {
+		log.Errorf("Error GET incident links on database: %s", err)
+		return
+	}
+
+	for _, link := range children {
+		if link.Type == LinkRelated {
+			continue
+		}
+
+		child, err := u.slackRepo.GetNewRelicIncidentByID(ctx, link.IncidentID, link.Channel)
+		if err != nil {
+			log.Errorf("Error GET incident on database: %s", err)
+			continue
+		}
+		if child.Status == "closed" {
+			continue
+		}
+
+		note := fmt.Sprintf(":white_check_mark: Resolved together with parent incident <%s|%s>", threadURL(parent), parent.Name)
+		if _, err := u.applyState(ctx, child, "closed", "diary", note); err != nil {
+			log.Errorf("Error store message to database: %s", err)
+		}
+	}
+}
+
+// linkFromForm extracts an optional link from the Ack form view state.
+func linkFromForm(values map[string]map[string]slack.BlockAction) (IncidentLink, bool) {
+	link := IncidentLink{}
+
+	for blockID, state := range values {
+		for _, value := range state {
+			switch blockID {
+			case LinkTypeBlock:
+				link.Type = value.SelectedOption.Value
+			case LinkParentBlock:
+				link.ParentID = strings.TrimSpace(value.Value)
+			}
+		}
+	}
+
+	if link.ParentID != "" && link.Type == "" {
+		link.Type = LinkRelated
+	}
+
+	return link, link.ParentID != ""
+}
+
+func isLinkBlock(blockID string) bool {
+	return blockID == LinkTypeBlock || blockID == LinkParentBlock
+}
+
+// threadURL links to the incident's parent Slack message.
+func threadURL(incident entitySlack.Incident) string {
+	if incident.MessageTimestamp == "" {
+		return incident.URL
+	}
+
+	return fmt.Sprintf("https://slack.com/archives/%s/p%s", incident.Channel, strings.Replace(incident.MessageTimestamp, ".", "", 1))
+}
@@ -0,0 +1,153 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLinkIncidents(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	parent, _ := u.ProcessIncident(ctx, testPayload("1", "open"))
+	child, _ := u.ProcessIncident(ctx, testPayload("2", "open"))
+
+	if _, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "2", Channel: "C1", Type: "blocks", ParentID: "1"}); err == nil {
+		t.Error("LinkIncidents accepted an invalid link type")
+	}
+	if _, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "2", Channel: "C1", Type: LinkCausedBy, ParentID: "2"}); err == nil {
+		t.Error("LinkIncidents linked an incident to itself")
+	}
+
+	link, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "2", Channel: "C1", Type: LinkCausedBy, ParentID: "1", CreatedBy: "U0ALICE01"})
+	if err != nil {
+		t.Fatalf("LinkIncidents: %v", err)
+	}
+	if link.ParentChannel != "C1" || link.CreatedAt.IsZero() {
+		t.Errorf("link = %+v, want the parent in the child's channel", link)
+	}
+
+	parents, children, err := u.GetIncidentLinks(ctx, "1", "C1")
+	if err != nil || len(parents) != 0 || len(children) != 1 || children[0].IncidentID != "2" {
+		t.Errorf("GetIncidentLinks(1) = %+v, %+v, %v", parents, children, err)
+	}
+	if !u.hasEvent(ctx, "1", "C1", EventLinked) || !u.hasEvent(ctx, "2", "C1", EventLinked) {
+		t.Error("linked event missing on parent or child")
+	}
+
+	// Both threads reference each other
+	notes := map[string]string{}
+	for _, call := range repo.Calls("ReplyMessageInThread") {
+		if strings.HasPrefix(call.Text, ":link:") {
+			notes[call.ThreadTimestamp] = call.Text
+		}
+	}
+	if !strings.Contains(notes[child.MessageTimestamp], "caused by") || !strings.Contains(notes[parent.MessageTimestamp], "Linked child incident") {
+		t.Errorf("link notes = %v", notes)
+	}
+}
+
+func TestLinkIncidentsRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+	for _, id := range []string{"1", "2", "3"} {
+		u.ProcessIncident(ctx, testPayload(id, "open"))
+	}
+
+	// 3 is caused by 2, which is a duplicate of 1
+	for _, link := range []IncidentLink{
+		{IncidentID: "2", Channel: "C1", Type: LinkDuplicateOf, ParentID: "1"},
+		{IncidentID: "3", Channel: "C1", Type: LinkCausedBy, ParentID: "2"},
+	} {
+		if _, err := u.LinkIncidents(ctx, link); err != nil {
+			t.Fatalf("LinkIncidents(%s -> %s): %v", link.IncidentID, link.ParentID, err)
+		}
+	}
+
+	if _, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "1", Channel: "C1", Type: LinkCausedBy, ParentID: "3"}); err == nil {
+		t.Error("LinkIncidents accepted a link closing a cycle")
+	}
+	if _, children, _ := u.GetIncidentLinks(ctx, "3", "C1"); len(children) != 0 {
+		t.Errorf("children of 3 = %+v, want the cyclic link not stored", children)
+	}
+
+	// Related links do not resolve anything, so they may point back
+	if _, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "1", Channel: "C1", Type: LinkRelated, ParentID: "3"}); err != nil {
+		t.Errorf("LinkIncidents(related): %v", err)
+	}
+}
+
+func TestResolvingParentResolvesChildren(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		u.ProcessIncident(ctx, testPayload(id, "open"))
+	}
+	for _, link := range []IncidentLink{
+		{IncidentID: "2", Channel: "C1", Type: LinkCausedBy, ParentID: "1"},
+		{IncidentID: "3", Channel: "C1", Type: LinkRelated, ParentID: "1"},
+		{IncidentID: "4", Channel: "C1", Type: LinkDuplicateOf, ParentID: "2"},
+	} {
+		if _, err := u.LinkIncidents(ctx, link); err != nil {
+			t.Fatalf("LinkIncidents(%s -> %s): %v", link.IncidentID, link.ParentID, err)
+		}
+	}
+
+	if _, err := u.ProcessIncident(ctx, testPayload("1", "closed")); err != nil {
+		t.Fatalf("ProcessIncident(closed): %v", err)
+	}
+
+	for id, want := range map[string]string{"1": "closed", "2": "closed", "3": "open", "4": "closed"} {
+		incident, err := repo.GetNewRelicIncidentByID(ctx, id, "C1")
+		if err != nil || incident.Status != want {
+			t.Errorf("incident %s = %q, %v; want %s", id, incident.Status, err, want)
+		}
+	}
+
+	// A child linked to a resolved parent is resolved right away
+	u.ProcessIncident(ctx, testPayload("5", "open"))
+	if _, err := u.LinkIncidents(ctx, IncidentLink{IncidentID: "5", Channel: "C1", Type: LinkDuplicateOf, ParentID: "1"}); err != nil {
+		t.Fatalf("LinkIncidents: %v", err)
+	}
+	if incident, _ := repo.GetNewRelicIncidentByID(ctx, "5", "C1"); incident.Status != "closed" {
+		t.Errorf("incident 5 = %q, want closed with its resolved parent", incident.Status)
+	}
+}
+
+func TestLinkAPIUsesAuthenticatedActor(t *testing.T) {
+	ctx := context.Background()
+	body := `{"channel": "C1", "type": "caused-by", "parent_id": "1", "created_by": "U0MALLORY"}`
+
+	u, _ := newTestUseCase(t, WithAuthenticator(func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") != "Bearer alice" {
+			return "", errors.New("unknown token")
+		}
+		return "U0ALICE01", nil
+	}))
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	u.ProcessIncident(ctx, testPayload("2", "open"))
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/2/links", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("link without token = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/incidents/2/links", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("link = %d %s", rec.Code, rec.Body)
+	}
+	link := IncidentLink{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &link); err != nil || link.CreatedBy != "U0ALICE01" {
+		t.Errorf("link = %+v, %v; want created by the authenticated user", link, err)
+	}
+}
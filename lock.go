@@ -0,0 +1,45 @@
+package slack
+
+import "sync"
+
+// keyedMutex serializes read-modify-write sequences per incident and channel.
+// Its zero value is ready to use. Locks are not reentrant, so each sequence
+// that may call another one uses its own keyedMutex.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[incidentKey]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	// waiters counts the holder and the goroutines waiting for the lock
+	waiters int
+}
+
+// lock locks the incident in channel and returns the function unlocking it.
+func (m *keyedMutex) lock(incidentID, channel string) func() {
+	key := incidentKey{incidentID, channel}
+
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[incidentKey]*keyedLock{}
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.waiters++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if l.waiters--; l.waiters == 0 {
+			delete(m.locks, key)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+var _ slackRepository = (*MemoryRepository)(nil)
+
+type incidentKey struct {
+	incidentID string
+	channel    string
+}
+
+// messageKey identifies a message like the database's unique index does:
+// Slack timestamps are only unique within a channel.
+type messageKey struct {
+	channel          string
+	messageTimestamp string
+}
+
+// storedMessage is a message row with the channel it was posted to.
+type storedMessage struct {
+	entitySlack.Message
+	channel string
+}
+
+// MemoryRepository is a fully functional in-memory slackRepository.
+// Storage behaves like the database schema (unique incident per channel,
+// messages joined by incident ID and channel) and the Slack API is served
+// by FakeSlack.
+type MemoryRepository struct {
+	*FakeSlack
+
+	mu        sync.RWMutex
+	incidents map[incidentKey]entitySlack.Incident
+	messages  map[messageKey]storedMessage
+	failures  map[string]error
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		FakeSlack: NewFakeSlack(),
+		incidents: map[incidentKey]entitySlack.Incident{},
+		messages:  map[messageKey]storedMessage{},
+		failures:  map[string]error{},
+	}
+}
+
+// Fail makes every following call to method, storage or Slack, return err.
+// A nil err clears the failure.
+func (m *MemoryRepository) Fail(method string, err error) {
+	m.FakeSlack.Fail(method, err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		delete(m.failures, method)
+		return
+	}
+	m.failures[method] = err
+}
+
+// Incidents returns a snapshot of all stored incidents ordered by start time.
+func (m *MemoryRepository) Incidents() []entitySlack.Incident {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	incidents := []entitySlack.Incident{}
+	for _, incident := range m.incidents {
+		incidents = append(incidents, m.withMessage(incident))
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].StartTime.Before(incidents[j].StartTime)
+	})
+	return incidents
+}
+
+func (m *MemoryRepository) fail(method string) error {
+	return m.failures[method]
+}
+
+// withMessage fills the message timestamp the same way the database join does.
+func (m *MemoryRepository) withMessage(incident entitySlack.Incident) entitySlack.Incident {
+	for key, message := range m.messages {
+		if message.IncidentID == incident.IncidentID && message.channel == incident.Channel {
+			incident.MessageTimestamp = key.messageTimestamp
+			incident.UserACK = message.UserACK
+		}
+	}
+	return incident
+}
+
+// message returns the message at messageTimestamp in channel, falling back to
+// a message stored without a channel like the database does.
+func (m *MemoryRepository) message(messageTimestamp, channel string) (messageKey, storedMessage, bool) {
+	for _, key := range []messageKey{{channel, messageTimestamp}, {"", messageTimestamp}} {
+		if message, ok := m.messages[key]; ok {
+			return key, message, true
+		}
+	}
+	return messageKey{}, storedMessage{}, false
+}
+
+func (m *MemoryRepository) GetNewRelicIncident(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	return m.getIncident("GetNewRelicIncident", incidentID, channel)
+}
+
+func (m *MemoryRepository) GetNewRelicIncidentByID(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	return m.getIncident("GetNewRelicIncidentByID", incidentID, channel)
+}
+
+func (m *MemoryRepository) getIncident(method, incidentID, channel string) (entitySlack.Incident, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.fail(method); err != nil {
+		return entitySlack.Incident{}, err
+	}
+
+	incident, ok := m.incidents[incidentKey{incidentID, channel}]
+	if !ok {
+		return entitySlack.Incident{}, ErrNotFound
+	}
+	return m.withMessage(incident), nil
+}
+
+func (m *MemoryRepository) GetNewRelicIncidentByMsgTimestamp(ctx context.Context, incidentID, messageTimestamp string) (entitySlack.Incident, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.fail("GetNewRelicIncidentByMsgTimestamp"); err != nil {
+		return entitySlack.Incident{}, err
+	}
+
+	for key, message := range m.messages {
+		if key.messageTimestamp != messageTimestamp || message.IncidentID != incidentID {
+			continue
+		}
+		if incident, ok := m.incidents[incidentKey{incidentID, message.channel}]; ok {
+			return m.withMessage(incident), nil
+		}
+	}
+	return entitySlack.Incident{}, ErrNotFound
+}
+
+func (m *MemoryRepository) InsertNewRelicIncident(ctx context.Context, incidentID string, conditionID int, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels string, startTime, recoverTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("InsertNewRelicIncident"); err != nil {
+		return err
+	}
+
+	key := incidentKey{incidentID, channel}
+	if _, ok := m.incidents[key]; ok {
+		return fmt.Errorf("incident %s already exists in channel %s", incidentID, channel)
+	}
+
+	m.incidents[key] = entitySlack.Incident{
+		IncidentID:  incidentID,
+		ConditionID: conditionID,
+		Name:        name,
+		URL:         url,
+		Description: description,
+		Owner:       owner,
+		GeneratedBy: generatedBy,
+		Status:      status,
+		Severity:    severity,
+		RootCause:   rootCause,
+		Channel:     channel,
+		Labels:      labels,
+		StartTime:   startTime,
+		RecoverTime: recoverTime,
+	}
+	return nil
+}
+
+func (m *MemoryRepository) UpdateNewRelicIncidentStatusByID(ctx context.Context, status, messageTimestamp, channel, incidentID string, recoverTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("UpdateNewRelicIncidentStatusByID"); err != nil {
+		return err
+	}
+
+	key := incidentKey{incidentID, channel}
+	incident, ok := m.incidents[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	incident.Status = status
+	incident.RecoverTime = recoverTime
+	m.incidents[key] = incident
+	return nil
+}
+
+// UpdateNewRelicIncidentByID sets the root cause in every channel, like the
+// database does.
+func (m *MemoryRepository) UpdateNewRelicIncidentByID(ctx context.Context, rootCause, incidentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("UpdateNewRelicIncidentByID"); err != nil {
+		return err
+	}
+
+	found := false
+	for key, incident := range m.incidents {
+		if key.incidentID == incidentID {
+			incident.RootCause = rootCause
+			m.incidents[key] = incident
+			found = true
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *MemoryRepository) UpdateNewRelicIncidentSeverityByID(ctx context.Context, severity, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("UpdateNewRelicIncidentSeverityByID"); err != nil {
+		return err
+	}
+
+	key := incidentKey{incidentID, channel}
+	incident, ok := m.incidents[key]
+	if !ok {
+		return ErrNotFound
+	}
+	incident.Severity = severity
+	m.incidents[key] = incident
+	return nil
+}
+
+// InsertMessage stores a message in the channel of the latest incident with
+// incidentID, for callers that do not know the channel.
+func (m *MemoryRepository) InsertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID string) error {
+	m.mu.RLock()
+	var latest entitySlack.Incident
+	for key, incident := range m.incidents {
+		if key.incidentID == incidentID && (latest.Channel == "" || incident.StartTime.After(latest.StartTime)) {
+			latest = incident
+		}
+	}
+	m.mu.RUnlock()
+
+	return m.InsertChannelMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID, latest.Channel)
+}
+
+func (m *MemoryRepository) InsertChannelMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("InsertMessage"); err != nil {
+		return err
+	}
+	key := messageKey{channel, messageTimestamp}
+	if _, ok := m.messages[key]; ok {
+		return fmt.Errorf("message %s already exists in channel %q", messageTimestamp, channel)
+	}
+
+	m.messages[key] = storedMessage{
+		Message: entitySlack.Message{
+			TriggerID:        triggerID,
+			Workspace:        workspace,
+			UserACK:          userACK,
+			MessageTimestamp: messageTimestamp,
+			IncidentID:       incidentID,
+		},
+		channel: channel,
+	}
+	return nil
+}
+
+func (m *MemoryRepository) UpdateMessageByTimestamp(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("UpdateMessageByTimestamp"); err != nil {
+		return err
+	}
+
+	key, message, ok := m.message(messageTimestamp, channel)
+	if !ok {
+		return ErrNotFound
+	}
+
+	message.TriggerID = triggerID
+	message.Workspace = workspace
+	message.UserACK = userACK
+	m.messages[key] = message
+	return nil
+}
+
+func (m *MemoryRepository) GetMessageByTimestamp(ctx context.Context, messageTimestamp, channel string) (entitySlack.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if err := m.fail("GetMessageByTimestamp"); err != nil {
+		return entitySlack.Message{}, err
+	}
+
+	_, message, ok := m.message(messageTimestamp, channel)
+	if !ok {
+		return entitySlack.Message{}, ErrNotFound
+	}
+	return message.Message, nil
+}
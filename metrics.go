@@ -0,0 +1,280 @@
+package slack
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Metrics holds the Prometheus instrumentation of the incident pipeline.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	incidentsReceived *prometheus.CounterVec
+	slackCalls        *prometheus.CounterVec
+	slackFailures     *prometheus.CounterVec
+	repositoryErrors  *prometheus.CounterVec
+	ackLatency        prometheus.Histogram
+	openIncidents     *openIncidentsCollector
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		incidentsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "diary",
+			Name:      "incidents_received_total",
+			Help:      "Incident webhooks received, by vendor and state.",
+		}, []string{"vendor", "state"}),
+		slackCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "diary",
+			Name:      "slack_api_calls_total",
+			Help:      "Slack API calls, by method.",
+		}, []string{"method"}),
+		slackFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "diary",
+			Name:      "slack_api_failures_total",
+			Help:      "Failed Slack API calls, by method.",
+		}, []string{"method"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "diary",
+			Name:      "repository_errors_total",
+			Help:      "Repository errors other than not found, by method.",
+		}, []string{"method"}),
+		ackLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "diary",
+			Name:      "ack_latency_seconds",
+			Help:      "Time from incident start to first acknowledgement.",
+			Buckets:   []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 14400},
+		}),
+		openIncidents: &openIncidentsCollector{desc: prometheus.NewDesc(
+			"diary_open_incidents",
+			"Incidents currently open or acknowledged, by channel.",
+			[]string{"channel"}, nil,
+		)},
+	}
+
+	m.registry.MustRegister(
+		m.incidentsReceived,
+		m.slackCalls,
+		m.slackFailures,
+		m.repositoryErrors,
+		m.ackLatency,
+		m.openIncidents,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// WithMetrics instruments the UseCase and its repository.
+func WithMetrics(m *Metrics) Option {
+	return func(u *UseCase) {
+		u.metrics = m
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// register adds the incidents of u to the collectors that read the
+// repository, so one Metrics can be shared by several UseCases.
+func (m *Metrics) register(u *UseCase) {
+	m.openIncidents.add(u)
+}
+
+func (m *Metrics) observeIncident(data entitySlack.NewRelicReplyThread) {
+	if m == nil {
+		return
+	}
+
+	m.incidentsReceived.WithLabelValues(data.GetVendor(), data.GetState()).Inc()
+}
+
+func (m *Metrics) observeAck(startTime time.Time) {
+	if m == nil || startTime.IsZero() {
+		return
+	}
+
+	m.ackLatency.Observe(time.Since(startTime).Seconds())
+}
+
+func (m *Metrics) observeSlack(method string, err error) {
+	m.slackCalls.WithLabelValues(method).Inc()
+	if err != nil {
+		m.slackFailures.WithLabelValues(method).Inc()
+	}
+}
+
+func (m *Metrics) observeRepository(method string, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, sql.ErrNoRows) {
+		m.repositoryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// openIncidentsCollector reads the open incident gauge from the repositories
+// of the registered UseCases at scrape time so it stays correct across
+// restarts. An incident seen by several UseCases is counted once.
+type openIncidentsCollector struct {
+	desc *prometheus.Desc
+
+	mu       sync.Mutex
+	useCases []*UseCase
+}
+
+func (c *openIncidentsCollector) add(u *UseCase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.useCases = append(c.useCases, u)
+}
+
+func (c *openIncidentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *openIncidentsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.mu.Lock()
+	useCases := append([]*UseCase(nil), c.useCases...)
+	c.mu.Unlock()
+
+	open := map[incidentKey]bool{}
+	for _, u := range useCases {
+		c.collectOpen(ctx, u, open)
+	}
+
+	perChannel := map[string]int{}
+	for key := range open {
+		perChannel[key.channel]++
+	}
+	for channel, count := range perChannel {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), channel)
+	}
+}
+
+// collectOpen adds the open and acknowledged incidents of u to open.
+func (c *openIncidentsCollector) collectOpen(ctx context.Context, u *UseCase, open map[incidentKey]bool) {
+	query := IncidentQuery{Status: []string{"open", "acknowledged"}, Limit: maxSearchLimit}
+	for {
+		incidents, total, err := u.SearchIncidents(ctx, query)
+		if err != nil {
+			if err != ErrSearchUnsupported {
+				log.Errorf("Failed collect open incidents because: %s", err)
+			}
+			return
+		}
+		for _, incident := range incidents {
+			open[incidentKey{incident.IncidentID, incident.Channel}] = true
+		}
+
+		query.Offset += len(incidents)
+		if len(incidents) == 0 || query.Offset >= total {
+			return
+		}
+	}
+}
+
+// instrumentedRepository counts Slack API calls and repository errors.
+type instrumentedRepository struct {
+	next    slackRepository
+	metrics *Metrics
+}
+
+func (r *instrumentedRepository) GetNewRelicIncident(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	incident, err := r.next.GetNewRelicIncident(ctx, incidentID, channel)
+	r.metrics.observeRepository("GetNewRelicIncident", err)
+	return incident, err
+}
+
+func (r *instrumentedRepository) GetNewRelicIncidentByID(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	incident, err := r.next.GetNewRelicIncidentByID(ctx, incidentID, channel)
+	r.metrics.observeRepository("GetNewRelicIncidentByID", err)
+	return incident, err
+}
+
+func (r *instrumentedRepository) GetNewRelicIncidentByMsgTimestamp(ctx context.Context, incidentID, messageTimestamp string) (entitySlack.Incident, error) {
+	incident, err := r.next.GetNewRelicIncidentByMsgTimestamp(ctx, incidentID, messageTimestamp)
+	r.metrics.observeRepository("GetNewRelicIncidentByMsgTimestamp", err)
+	return incident, err
+}
+
+func (r *instrumentedRepository) InsertNewRelicIncident(ctx context.Context, incidentID string, conditionID int, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels string, startTime, recoverTime time.Time) error {
+	err := r.next.InsertNewRelicIncident(ctx, incidentID, conditionID, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels, startTime, recoverTime)
+	r.metrics.observeRepository("InsertNewRelicIncident", err)
+	return err
+}
+
+func (r *instrumentedRepository) UpdateNewRelicIncidentStatusByID(ctx context.Context, status, messageTimestamp, channel, incidentID string, recoverTime time.Time) error {
+	err := r.next.UpdateNewRelicIncidentStatusByID(ctx, status, messageTimestamp, channel, incidentID, recoverTime)
+	r.metrics.observeRepository("UpdateNewRelicIncidentStatusByID", err)
+	return err
+}
+
+func (r *instrumentedRepository) UpdateNewRelicIncidentByID(ctx context.Context, rootCause, incidentID string) error {
+	err := r.next.UpdateNewRelicIncidentByID(ctx, rootCause, incidentID)
+	r.metrics.observeRepository("UpdateNewRelicIncidentByID", err)
+	return err
+}
+
+func (r *instrumentedRepository) InsertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID string) error {
+	err := r.next.InsertMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID)
+	r.metrics.observeRepository("InsertMessage", err)
+	return err
+}
+
+func (r *instrumentedRepository) UpdateMessageByTimestamp(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, channel string) error {
+	err := r.next.UpdateMessageByTimestamp(ctx, triggerID, workspace, userACK, messageTimestamp, channel)
+	r.metrics.observeRepository("UpdateMessageByTimestamp", err)
+	return err
+}
+
+func (r *instrumentedRepository) GetMessageByTimestamp(ctx context.Context, messageTimestamp, channel string) (entitySlack.Message, error) {
+	message, err := r.next.GetMessageByTimestamp(ctx, messageTimestamp, channel)
+	r.metrics.observeRepository("GetMessageByTimestamp", err)
+	return message, err
+}
+
+func (r *instrumentedRepository) SendMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	respChannel, ts, err := r.next.SendMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+	r.metrics.observeSlack("SendMessage", err)
+	return respChannel, ts, err
+}
+
+func (r *instrumentedRepository) UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	respChannel, ts, err := r.next.UpdateMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+	r.metrics.observeSlack("UpdateMessage", err)
+	return respChannel, ts, err
+}
+
+func (r *instrumentedRepository) ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error) {
+	respChannel, ts, err := r.next.ReplyMessageInThread(ctx, channel, message, color, messageTimestamp, url)
+	r.metrics.observeSlack("ReplyMessageInThread", err)
+	return respChannel, ts, err
+}
+
+func (r *instrumentedRepository) SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	result, err := r.next.SubmitButtonAction(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal)
+	r.metrics.observeSlack("SubmitButtonAction", err)
+	return result, err
+}
+
+func (r *instrumentedRepository) ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	result, err := r.next.ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url, replaceOriginal)
+	r.metrics.observeSlack("ReplaceMessage", err)
+	return result, err
+}
@@ -0,0 +1,49 @@
+package slack
+
+import (
+	"context"
+	"testing"
+)
+
+// openIncidents returns the diary_open_incidents gauge by channel.
+func openIncidents(t *testing.T, m *Metrics) map[string]float64 {
+	t.Helper()
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	gauge := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "diary_open_incidents" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			gauge[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+	return gauge
+}
+
+func TestMetricsSharedByUseCases(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetrics()
+
+	first, repo := newTestUseCase(t, WithMetrics(m))
+	// A second UseCase on the same repository must not double count
+	second := New(repo, WithConfig(first.config), WithMetrics(m))
+	other, _ := newTestUseCase(t, WithMetrics(m))
+
+	first.ProcessIncident(ctx, testPayload("1", "open"))
+	second.ProcessIncident(ctx, testPayload("2", "open"))
+	inC2 := testPayload("3", "open")
+	inC2.Channel = "C2"
+	other.ProcessIncident(ctx, inC2)
+	other.ProcessIncident(ctx, testPayload("4", "closed"))
+
+	gauge := openIncidents(t, m)
+	if gauge["C1"] != 2 || gauge["C2"] != 1 || len(gauge) != 2 {
+		t.Errorf("diary_open_incidents = %v, want C1=2 C2=1", gauge)
+	}
+}
@@ -0,0 +1,283 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Notification is the channel-agnostic view of an incident update that is
+// delivered to every non-Slack notifier routed to the incident's channel.
+//
+// It is also the body of outbound webhooks (see WebhookPayload), so field
+// names are part of the public schema and must not be renamed.
+//
+// Severity is the vendor value as sent by the alerting tool and
+// NormalizedSeverity its SEV1-SEV4 classification.
+type Notification struct {
+	IncidentID         string     `json:"incident_id"`
+	ConditionID        int        `json:"condition_id"`
+	Title              string     `json:"title"`
+	Name               string     `json:"name"`
+	State              string     `json:"state"`
+	Severity           string     `json:"severity"`
+	NormalizedSeverity string     `json:"normalized_severity"`
+	Owner              string     `json:"owner"`
+	Vendor             string     `json:"vendor"`
+	Channel            string     `json:"channel"`
+	URL                string     `json:"url"`
+	Body               string     `json:"body"`
+	Labels             string     `json:"labels"`
+	StartTime          time.Time  `json:"start_time"`
+	RecoverTime        *time.Time `json:"recover_time,omitempty"`
+}
+
+// Notifier delivers an incident notification to a single destination.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotificationRouter maps a Slack channel to the extra notifiers that should
+// receive its incidents. The "*" key applies to every channel.
+type NotificationRouter map[string][]Notifier
+
+// Route returns the notifiers for the given channel.
+func (r NotificationRouter) Route(channel string) []Notifier {
+	notifiers := []Notifier{}
+	notifiers = append(notifiers, r[channel]...)
+	notifiers = append(notifiers, r["*"]...)
+
+	return notifiers
+}
+
+// Option configures optional UseCase dependencies.
+type Option func(*UseCase)
+
+// WithNotifiers routes incident updates to notifiers besides Slack.
+func WithNotifiers(router NotificationRouter) Option {
+	return func(u *UseCase) {
+		u.notifiers = router
+	}
+}
+
+func (u *UseCase) notify(ctx context.Context, data entitySlack.NewRelicReplyThread, incident entitySlack.Incident) {
+	notifiers := u.notifiers.Route(data.GetChannel())
+	if len(notifiers) == 0 {
+		return
+	}
+
+	n := Notification{
+		IncidentID:         data.GetIncidentID(),
+		ConditionID:        data.GetConditionID(),
+		Title:              data.GetTitle(),
+		Name:               data.GetIncidentName(),
+		State:              data.GetState(),
+		Severity:           data.GetSeverity(),
+		NormalizedSeverity: string(u.severityOf(data)),
+		Owner:              data.GetOwner(),
+		Vendor:             data.GetVendor(),
+		Channel:            data.GetChannel(),
+		URL:                data.GetURL(),
+		Body:               data.GetBody(),
+		Labels:             data.GetLabels(),
+		StartTime:          incident.StartTime,
+	}
+	if !incident.RecoverTime.IsZero() {
+		recoverTime := incident.RecoverTime
+		n.RecoverTime = &recoverTime
+	}
+
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			log.Errorf("Failed send notification for incident %s because: %s", n.IncidentID, err)
+		}
+	}
+}
+
+// EmailNotifier sends incident notifications over SMTP.
+// Auth may be nil for local SMTP sinks that accept unauthenticated mail.
+// Timeout bounds the whole SMTP session, defaulting to defaultEmailTimeout;
+// an earlier deadline of the Notify context wins.
+type EmailNotifier struct {
+	Addr    string
+	From    string
+	To      []string
+	Auth    smtp.Auth
+	Timeout time.Duration
+}
+
+const defaultEmailTimeout = 10 * time.Second
+
+// headerBreaks are replaced in header values so incident data cannot start new headers.
+var headerBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// Notify delivers n like smtp.SendMail, but on a connection that honours the
+// timeout and ctx, so a hung SMTP server cannot block incident processing.
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultEmailTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// Unblock reads and writes as soon as ctx is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(e.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Auth != nil {
+		if err := c.Auth(e.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// message renders n as a plain text mail. The subject comes from the vendor
+// payload, so line breaks are removed and non-ASCII text is MIME encoded.
+func (e *EmailNotifier) message(n Notification) []byte {
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(n.State), n.Title)
+	if n.Title == "" {
+		subject = fmt.Sprintf("[%s] %s", strings.ToUpper(n.State), n.Name)
+	}
+	subject = mime.QEncoding.Encode("UTF-8", headerBreaks.Replace(subject))
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", e.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&body, "Incident : %s\r\n", n.IncidentID)
+	fmt.Fprintf(&body, "Status   : %s\r\n", n.State)
+	if n.NormalizedSeverity != "" {
+		fmt.Fprintf(&body, "Severity : %s (%s)\r\n", n.NormalizedSeverity, n.Severity)
+	} else {
+		fmt.Fprintf(&body, "Severity : %s\r\n", n.Severity)
+	}
+	fmt.Fprintf(&body, "Owner    : %s\r\n", n.Owner)
+	fmt.Fprintf(&body, "Started  : %s\r\n", n.StartTime.Format(time.RFC1123))
+	if n.RecoverTime != nil {
+		fmt.Fprintf(&body, "Recovered: %s\r\n", n.RecoverTime.Format(time.RFC1123))
+	}
+	fmt.Fprintf(&body, "Link     : %s\r\n\r\n%s\r\n", n.URL, n.Body)
+
+	return body.Bytes()
+}
+
+// WebhookPayload is the JSON document POSTed by WebhookNotifier:
+//
+//	{
+//	  "version": 1,
+//	  "event": "incident.open" | "incident.acknowledged" | "incident.closed",
+//	  "sent_at": "2024-01-02T15:04:05Z",
+//	  "incident": { ...Notification... }
+//	}
+//
+// Receivers should ignore unknown fields; new fields are added without
+// bumping version, breaking changes bump it.
+type WebhookPayload struct {
+	Version  int          `json:"version"`
+	Event    string       `json:"event"`
+	SentAt   time.Time    `json:"sent_at"`
+	Incident Notification `json:"incident"`
+}
+
+// WebhookNotifier POSTs a WebhookPayload to URL. Any non-2xx response is an error.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(WebhookPayload{
+		Version:  1,
+		Event:    "incident." + n.State,
+		SentAt:   time.Now().UTC(),
+		Incident: n,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", w.URL, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailSubjectCannotInjectHeaders(t *testing.T) {
+	e := &EmailNotifier{From: "diary@example.com", To: []string{"oncall@example.com"}}
+
+	message := string(e.message(Notification{
+		State: "open",
+		Title: "CPU high\r\nBcc: attacker@example.com\nX-Injected: yes",
+	}))
+
+	headers, _, _ := strings.Cut(message, "\r\n\r\n")
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") || strings.HasPrefix(line, "X-Injected:") {
+			t.Errorf("title injected header %q", line)
+		}
+	}
+	if !strings.Contains(headers, "Subject: [OPEN] CPU high Bcc: attacker@example.com X-Injected: yes\r\n") {
+		t.Errorf("headers = %q", headers)
+	}
+}
+
+func TestEmailSubjectEncodesNonASCII(t *testing.T) {
+	e := &EmailNotifier{From: "diary@example.com", To: []string{"oncall@example.com"}}
+
+	message := string(e.message(Notification{State: "open", Title: "Latensi tinggi — checkout"}))
+	if !strings.Contains(message, "Subject: =?UTF-8?q?") {
+		t.Errorf("subject is not MIME encoded: %q", message)
+	}
+}
+
+func TestEmailShowsBothSeverities(t *testing.T) {
+	e := &EmailNotifier{From: "diary@example.com", To: []string{"oncall@example.com"}}
+
+	message := string(e.message(Notification{State: "open", Severity: "critical", NormalizedSeverity: string(SEV1)}))
+	if !strings.Contains(message, "Severity : SEV1 (critical)\r\n") {
+		t.Errorf("message = %q, want the normalized and vendor severity", message)
+	}
+}
+
+// smtpSink accepts unauthenticated mail on a local port and sends every
+// received message to the returned channel.
+func smtpSink(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch verb := strings.ToUpper(strings.Fields(line + " x")[0]); verb {
+		case "EHLO", "HELO", "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			messages <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailNotifierSendsToSMTPSink(t *testing.T) {
+	addr, messages := smtpSink(t)
+	e := &EmailNotifier{Addr: addr, From: "diary@example.com", To: []string{"oncall@example.com"}}
+
+	if err := e.Notify(context.Background(), Notification{IncidentID: "42", State: "open", Title: "CPU high"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	message := <-messages
+	if !strings.Contains(message, "Subject: [OPEN] CPU high\r\n") || !strings.Contains(message, "Incident : 42\r\n") {
+		t.Errorf("message = %q", message)
+	}
+}
+
+func TestEmailNotifierTimesOut(t *testing.T) {
+	// A server that accepts connections but never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conns := []net.Conn{}
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	e := &EmailNotifier{Addr: l.Addr().String(), From: "diary@example.com", To: []string{"oncall@example.com"}, Timeout: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := e.Notify(ctx, Notification{IncidentID: "42", State: "open"}); err == nil {
+		t.Fatal("Notify to a hung server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify returned after %s, want the context deadline", elapsed)
+	}
+
+	e.Timeout = 100 * time.Millisecond
+	start = time.Now()
+	if err := e.Notify(context.Background(), Notification{IncidentID: "42", State: "open"}); err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("Notify with Timeout = %v after %s", err, time.Since(start))
+	}
+}
+
+func TestWebhookRecoverTime(t *testing.T) {
+	bodies := []map[string]json.RawMessage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := struct {
+			Incident map[string]json.RawMessage `json:"incident"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode webhook: %v", err)
+		}
+		bodies = append(bodies, payload.Incident)
+	}))
+	defer server.Close()
+
+	webhook := &WebhookNotifier{URL: server.URL}
+	recoverTime := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, n := range []Notification{
+		{IncidentID: "1", State: "open"},
+		{IncidentID: "1", State: "closed", RecoverTime: &recoverTime},
+	} {
+		if err := webhook.Notify(context.Background(), n); err != nil {
+			t.Fatalf("Notify(%s): %v", n.State, err)
+		}
+	}
+
+	if _, ok := bodies[0]["recover_time"]; ok {
+		t.Errorf("open incident has recover_time %s", bodies[0]["recover_time"])
+	}
+	if got := string(bodies[1]["recover_time"]); got != `"2024-01-02T15:04:05Z"` {
+		t.Errorf("closed incident recover_time = %s", got)
+	}
+}
+
+func TestWebhookNormalizedSeverity(t *testing.T) {
+	incidents := []Notification{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := WebhookPayload{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode webhook: %v", err)
+		}
+		incidents = append(incidents, payload.Incident)
+	}))
+	defer server.Close()
+
+	u, _ := newTestUseCase(t, WithNotifiers(NotificationRouter{"*": {&WebhookNotifier{URL: server.URL}}}))
+	u.ProcessIncident(context.Background(), testPayload("42", "open"))
+
+	// The vendor value keeps its field, the classification is added beside it
+	if len(incidents) != 1 || incidents[0].Severity != "critical" || incidents[0].NormalizedSeverity != string(SEV1) {
+		t.Errorf("webhook incidents = %+v, want vendor severity critical normalized to SEV1", incidents)
+	}
+}
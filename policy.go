@@ -0,0 +1,470 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// EventDeferred is recorded when a notification policy holds back an incident
+// until the next summary.
+const EventDeferred = "deferred"
+
+// EventSummarized is recorded once a deferred incident's summary was
+// attempted, ending the deferral.
+const EventSummarized = "summarized"
+
+// Notification policy periods.
+const (
+	PeriodBusinessHours = "business_hours"
+	PeriodAfterHours    = "after_hours"
+	PeriodHoliday       = "holiday"
+)
+
+var defaultBusinessDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// NotificationPolicy decides, per channel, which incidents are posted and
+// paged right away. Each period names the lowest severity notified
+// immediately; less severe incidents are only stored and listed in a summary
+// at the start of the next business hours. An empty period notifies
+// everything, and SEV1 is never deferred.
+//
+// The holidays file lists one YYYY-MM-DD date per line, optionally followed
+// by a name; blank lines and lines starting with # are ignored.
+type NotificationPolicy struct {
+	Channel       string   `json:"channel"`
+	Timezone      string   `json:"timezone,omitempty"`
+	BusinessStart string   `json:"business_start"`
+	BusinessEnd   string   `json:"business_end"`
+	BusinessDays  []string `json:"business_days,omitempty"`
+	HolidaysFile  string   `json:"holidays_file,omitempty"`
+	BusinessHours string   `json:"business_hours,omitempty"`
+	AfterHours    string   `json:"after_hours,omitempty"`
+	Holidays      string   `json:"holidays,omitempty"`
+
+	location *time.Location
+	start    time.Duration
+	end      time.Duration
+	days     map[time.Weekday]bool
+	holidays map[string]string
+}
+
+func (p *NotificationPolicy) validate() error {
+	if p.Channel == "" {
+		return fmt.Errorf("notification policy: channel is required")
+	}
+
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return fmt.Errorf("notification policy %s: %w", p.Channel, err)
+	}
+	p.location = location
+
+	if p.start, err = parseClock(p.BusinessStart); err != nil {
+		return fmt.Errorf("notification policy %s: business_start: %w", p.Channel, err)
+	}
+	if p.end, err = parseClock(p.BusinessEnd); err != nil {
+		return fmt.Errorf("notification policy %s: business_end: %w", p.Channel, err)
+	}
+	if p.end <= p.start {
+		return fmt.Errorf("notification policy %s: business_end must be after business_start", p.Channel)
+	}
+
+	days := p.BusinessDays
+	if len(days) == 0 {
+		days = defaultBusinessDays
+	}
+	p.days = map[time.Weekday]bool{}
+	for _, day := range days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return fmt.Errorf("notification policy %s: invalid business day %q", p.Channel, day)
+		}
+		p.days[weekday] = true
+	}
+
+	for _, severity := range []string{p.BusinessHours, p.AfterHours, p.Holidays} {
+		if _, ok := ParseSeverity(severity); severity != "" && !ok {
+			return fmt.Errorf("notification policy %s: invalid severity %q", p.Channel, severity)
+		}
+	}
+
+	p.holidays = map[string]string{}
+	if p.HolidaysFile != "" {
+		if p.holidays, err = loadHolidays(p.HolidaysFile); err != nil {
+			return fmt.Errorf("notification policy %s: %w", p.Channel, err)
+		}
+	}
+
+	return nil
+}
+
+// parseClock parses a HH:MM time of day.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func loadHolidays(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	holidays := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		date, name, _ := strings.Cut(text, " ")
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("%s:%d: %q is not YYYY-MM-DD", path, line, date)
+		}
+		holidays[date] = strings.TrimSpace(name)
+	}
+
+	return holidays, scanner.Err()
+}
+
+// Period returns whether t falls in business hours, after hours or on a holiday.
+func (p *NotificationPolicy) Period(t time.Time) string {
+	local := t.In(p.location)
+	if _, ok := p.holidays[local.Format(time.DateOnly)]; ok {
+		return PeriodHoliday
+	}
+	if !p.days[local.Weekday()] {
+		return PeriodAfterHours
+	}
+
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if clock < p.start || clock >= p.end {
+		return PeriodAfterHours
+	}
+
+	return PeriodBusinessHours
+}
+
+// Defers reports whether an incident of severity raised at t waits for the summary.
+func (p *NotificationPolicy) Defers(severity Severity, t time.Time) bool {
+	if severity == SEV1 {
+		return false
+	}
+
+	var min string
+	switch p.Period(t) {
+	case PeriodBusinessHours:
+		min = p.BusinessHours
+	case PeriodAfterHours:
+		min = p.AfterHours
+	case PeriodHoliday:
+		min = p.Holidays
+	}
+	if min == "" {
+		return false
+	}
+
+	return severity.Rank() < NormalizeSeverity(min).Rank()
+}
+
+// Policy returns the notification policy of channel, falling back to the "*" policy.
+func (c *Config) Policy(channel string) (*NotificationPolicy, bool) {
+	var fallback *NotificationPolicy
+	for i := range c.NotificationPolicies {
+		switch c.NotificationPolicies[i].Channel {
+		case channel:
+			return &c.NotificationPolicies[i], true
+		case "*":
+			fallback = &c.NotificationPolicies[i]
+		}
+	}
+
+	return fallback, fallback != nil
+}
+
+// deferIncident stores an incident without posting or paging when its
+// channel's policy defers it, and keeps already deferred incidents quiet
+// until the summary. An incident is deferred from its deferred event until
+// the summarized event recorded once its summary was attempted, so the state
+// survives restarts. It reports whether the incident was handled here.
+func (u *UseCase) deferIncident(ctx context.Context, data entitySlack.NewRelicReplyThread, incident entitySlack.Incident) bool {
+	if incident.MessageTimestamp != "" {
+		return false
+	}
+
+	policy, ok := u.config.Load().Policy(data.GetChannel())
+	severity := u.severityOf(data)
+	now := time.Now()
+	if !ok || !policy.Defers(severity, now) {
+		return false
+	}
+
+	if incident.IncidentID == "" {
+		if err := u.RegisterIncident(ctx, data); err != nil {
+			log.Errorf("Failed send incident request: %v", err)
+			return false
+		}
+
+		u.recordEvent(ctx, IncidentEvent{
+			IncidentID: data.GetIncidentID(),
+			Channel:    data.GetChannel(),
+			Type:       EventStateChanged,
+			State:      data.GetState(),
+			Actor:      data.GetVendor(),
+		})
+	} else {
+		var recoverTime time.Time
+		if data.GetState() == "closed" || data.GetState() == "acknowledged" {
+			recoverTime = time.Now().Local()
+		}
+
+		if err := u.slackRepo.UpdateNewRelicIncidentStatusByID(ctx, data.GetState(), "", data.GetChannel(), data.GetIncidentID(), recoverTime); err != nil {
+			log.Errorf("Error store message to database: %s", err)
+		}
+		if incident.Status != data.GetState() {
+			u.recordEvent(ctx, IncidentEvent{
+				IncidentID:    data.GetIncidentID(),
+				Channel:       data.GetChannel(),
+				Type:          EventStateChanged,
+				State:         data.GetState(),
+				PreviousState: incident.Status,
+				Actor:         data.GetVendor(),
+			})
+		}
+
+		// Already deferred: keep its state current until the summary
+		if u.deferralPending(ctx, incident.IncidentID, incident.Channel) {
+			return true
+		}
+	}
+
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: data.GetIncidentID(),
+		Channel:    data.GetChannel(),
+		Type:       EventDeferred,
+		State:      data.GetState(),
+		Actor:      data.GetVendor(),
+		Detail:     fmt.Sprintf("%s, %s", severity, strings.ReplaceAll(policy.Period(now), "_", " ")),
+	})
+
+	return true
+}
+
+// deferralPending reports whether the incident was deferred after its last
+// summary, if any.
+func (u *UseCase) deferralPending(ctx context.Context, incidentID, channel string) bool {
+	events, err := u.events.GetIncidentEvents(ctx, incidentID, channel)
+	if err != nil {
+		log.Errorf("Error GET incident events on database: %s", err)
+		return false
+	}
+
+	pending := false
+	for _, event := range events {
+		switch event.Type {
+		case EventDeferred:
+			pending = true
+		case EventSummarized:
+			pending = false
+		}
+	}
+
+	return pending
+}
+
+// SendDeferredSummaries posts one summary per channel of the incidents
+// deferred since its last business hours, once the channel is back in
+// business hours. Incidents still unresolved are then posted as regular
+// incident messages so they can be acknowledged. Deferred incidents are the
+// stored incidents without a Slack message whose last deferral was not
+// summarized yet.
+func (u *UseCase) SendDeferredSummaries(ctx context.Context) error {
+	deferred := []entitySlack.Incident{}
+	query := IncidentQuery{Unposted: true, Limit: maxSearchLimit}
+	for {
+		incidents, total, err := u.SearchIncidents(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, incident := range incidents {
+			if u.deferralPending(ctx, incident.IncidentID, incident.Channel) {
+				deferred = append(deferred, incident)
+			}
+		}
+
+		query.Offset += len(incidents)
+		if len(incidents) == 0 || query.Offset >= total {
+			break
+		}
+	}
+
+	// Oldest first
+	byChannel := map[string][]entitySlack.Incident{}
+	channels := []string{}
+	for i := len(deferred) - 1; i >= 0; i-- {
+		incident := deferred[i]
+		if _, ok := byChannel[incident.Channel]; !ok {
+			channels = append(channels, incident.Channel)
+		}
+		byChannel[incident.Channel] = append(byChannel[incident.Channel], incident)
+	}
+
+	now := time.Now()
+	for _, channel := range channels {
+		// Without a policy nothing is deferred; the next event posts the incident
+		policy, ok := u.config.Load().Policy(channel)
+		if !ok || policy.Period(now) != PeriodBusinessHours {
+			continue
+		}
+
+		u.sendDeferredSummary(ctx, channel, byChannel[channel])
+	}
+
+	return nil
+}
+
+// sendDeferredSummary summarizes the deferred incidents of channel. Each
+// incident is summarized once: a failed post is not retried, and the
+// incident is posted by its next event instead.
+func (u *UseCase) sendDeferredSummary(ctx context.Context, channel string, deferred []entitySlack.Incident) {
+	lines := []string{fmt.Sprintf(":sunrise: *%d incident(s) deferred outside business hours*", len(deferred))}
+	worst := SEV4
+	for _, incident := range deferred {
+		s := NormalizeSeverity(incident.Severity)
+		if s.Rank() > worst.Rank() {
+			worst = s
+		}
+		lines = append(lines, fmt.Sprintf("• %s *%s* <%s|%s> : `%s` since %s", s.Emoji(), s, incident.URL, incident.Name, incident.Status, incident.StartTime.Format(time.RFC1123)))
+	}
+
+	_, ts, err := u.slackRepo.SendMessage(ctx, channel, strings.Join(lines, "\n"), worst.Color(), "", "", "")
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", channel, err)
+	}
+
+	for _, incident := range deferred {
+		u.summarizeDeferredIncident(ctx, incident, ts)
+	}
+}
+
+// summarizeDeferredIncident posts a deferred incident after its summary at
+// summaryTimestamp, if the summary was sent, and ends its deferral. It holds
+// the incident's lock so a concurrent event cannot post it too.
+func (u *UseCase) summarizeDeferredIncident(ctx context.Context, incident entitySlack.Incident, summaryTimestamp string) {
+	unlock := u.incidentLocks.lock(incident.IncidentID, incident.Channel)
+	defer unlock()
+
+	current, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incident.IncidentID, incident.Channel)
+	if err != nil {
+		log.Errorf("Error GET incident on database: %s", err)
+		return
+	}
+	// Posted by an event since the summary started
+	if current.MessageTimestamp != "" || !u.deferralPending(ctx, current.IncidentID, current.Channel) {
+		return
+	}
+
+	detail := ""
+	switch {
+	case summaryTimestamp == "":
+		detail = "summary not sent"
+	case current.Status != "closed":
+		u.postDeferredIncident(ctx, current)
+	default:
+		u.postResolvedDeferredIncident(ctx, current, summaryTimestamp)
+	}
+
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: current.IncidentID,
+		Channel:    current.Channel,
+		Type:       EventSummarized,
+		State:      current.Status,
+		Detail:     detail,
+	})
+}
+
+// postResolvedDeferredIncident replies with a deferred incident that was
+// resolved before its summary in the summary thread, and keeps the reply as
+// its message so it is not deferred anymore.
+func (u *UseCase) postResolvedDeferredIncident(ctx context.Context, incident entitySlack.Incident, summaryTimestamp string) {
+	title := u.GetTitle(incident.GeneratedBy, incident.Status, incident.Name, incident.URL)
+	message := withSeverity(u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime), incident.Severity)
+	_, ts, err := u.sendNow(incident.Channel, summaryTimestamp).ReplyMessageInThread(ctx, incident.Channel, title+message, u.GetColorStr(incident.Status, incident.Severity), summaryTimestamp, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", incident.Channel, err)
+		return
+	}
+	if ts == "" {
+		return
+	}
+
+	if err := u.insertMessage(ctx, "", "", "", ts, incident.IncidentID, incident.Channel); err != nil {
+		log.Errorf("Error store message to database: %s", err)
+	}
+}
+
+// postDeferredIncident posts the parent message of a deferred incident that
+// is still unresolved when its summary is sent.
+func (u *UseCase) postDeferredIncident(ctx context.Context, incident entitySlack.Incident) {
+	title := u.GetTitle(incident.GeneratedBy, incident.Status, incident.Name, incident.URL)
+	message := withSeverity(u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime), incident.Severity)
+	_, ts, err := u.slackRepo.SendMessage(ctx, incident.Channel, title+message, u.GetColorStr(incident.Status, incident.Severity), "", incident.GeneratedBy, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", incident.Channel, err)
+		return
+	}
+
+	if err := u.insertMessage(ctx, "", "", "", ts, incident.IncidentID, incident.Channel); err != nil {
+		log.Errorf("Error store message to database: %s", err)
+		return
+	}
+
+	posted, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incident.IncidentID, incident.Channel)
+	if err != nil {
+		log.Errorf("Error GET incident on database: %s", err)
+		return
+	}
+
+	condition, _ := u.config.Load().Catalog().ByID(posted.ConditionID)
+	u.postIncidentActions(ctx, condition, posted)
+}
+
+// RunDeferredSummaries sends due deferred summaries every interval until ctx is done.
+func (u *UseCase) RunDeferredSummaries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.SendDeferredSummaries(ctx); err != nil {
+				log.Errorf("Failed send deferred summaries because: %s", err)
+			}
+		}
+	}
+}
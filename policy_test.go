@@ -0,0 +1,274 @@
+package slack
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPolicy validates a policy in America/New_York with business hours
+// 09:00-18:00 on weekdays.
+func newPolicy(t *testing.T, holidays string) NotificationPolicy {
+	t.Helper()
+
+	policy := NotificationPolicy{
+		Channel:       "C1",
+		Timezone:      "America/New_York",
+		BusinessStart: "09:00",
+		BusinessEnd:   "18:00",
+		BusinessHours: "SEV3",
+		AfterHours:    "SEV2",
+		Holidays:      "SEV1",
+	}
+	if holidays != "" {
+		policy.HolidaysFile = filepath.Join(t.TempDir(), "holidays.txt")
+		if err := os.WriteFile(policy.HolidaysFile, []byte(holidays), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := policy.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	return policy
+}
+
+func TestPolicyPeriod(t *testing.T) {
+	policy := newPolicy(t, "# US holidays\n\n2024-07-04 Independence Day\n")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	for _, tc := range []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"before business hours", time.Date(2024, 7, 1, 8, 59, 0, 0, newYork), PeriodAfterHours},
+		{"business start", time.Date(2024, 7, 1, 9, 0, 0, 0, newYork), PeriodBusinessHours},
+		{"last business minute", time.Date(2024, 7, 1, 17, 59, 59, 0, newYork), PeriodBusinessHours},
+		{"business end", time.Date(2024, 7, 1, 18, 0, 0, 0, newYork), PeriodAfterHours},
+		{"weekend", time.Date(2024, 7, 6, 12, 0, 0, 0, newYork), PeriodAfterHours},
+		{"holiday", time.Date(2024, 7, 4, 12, 0, 0, 0, newYork), PeriodHoliday},
+		{"holiday starts at local midnight", time.Date(2024, 7, 4, 3, 59, 0, 0, time.UTC), PeriodAfterHours},
+		{"holiday in local time", time.Date(2024, 7, 4, 4, 0, 0, 0, time.UTC), PeriodHoliday},
+		// 13:30 UTC is 08:30 EST before and 09:30 EDT after the DST change of 2024-03-10
+		{"before DST", time.Date(2024, 3, 8, 13, 30, 0, 0, time.UTC), PeriodAfterHours},
+		{"after DST", time.Date(2024, 3, 11, 13, 30, 0, 0, time.UTC), PeriodBusinessHours},
+		// 22:30 UTC is 17:30 EST before and 18:30 EDT after
+		{"end before DST", time.Date(2024, 3, 8, 22, 30, 0, 0, time.UTC), PeriodBusinessHours},
+		{"end after DST", time.Date(2024, 3, 11, 22, 30, 0, 0, time.UTC), PeriodAfterHours},
+		// 2024-11-04 is back on EST: 13:30 UTC is 08:30
+		{"after DST ends", time.Date(2024, 11, 4, 13, 30, 0, 0, time.UTC), PeriodAfterHours},
+	} {
+		if got := policy.Period(tc.t); got != tc.want {
+			t.Errorf("%s: Period(%s) = %s, want %s", tc.name, tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestPolicyDefers(t *testing.T) {
+	policy := newPolicy(t, "2024-07-04\n")
+	newYork, _ := time.LoadLocation("America/New_York")
+	business := time.Date(2024, 7, 1, 12, 0, 0, 0, newYork)
+	afterHours := time.Date(2024, 7, 1, 18, 0, 0, 0, newYork)
+	holiday := time.Date(2024, 7, 4, 12, 0, 0, 0, newYork)
+
+	for _, tc := range []struct {
+		severity Severity
+		t        time.Time
+		want     bool
+	}{
+		{SEV3, business, false},
+		{SEV4, business, true},
+		{SEV2, afterHours, false},
+		{SEV3, afterHours, true},
+		{SEV1, holiday, false},
+		{SEV2, holiday, true},
+	} {
+		if got := policy.Defers(tc.severity, tc.t); got != tc.want {
+			t.Errorf("Defers(%s, %s) = %v, want %v", tc.severity, tc.t, got, tc.want)
+		}
+	}
+
+	// An empty period notifies everything
+	policy.Holidays = ""
+	if policy.Defers(SEV4, holiday) {
+		t.Error("empty holidays period deferred SEV4")
+	}
+}
+
+func TestInvalidHolidays(t *testing.T) {
+	policy := NotificationPolicy{Channel: "C1", BusinessStart: "09:00", BusinessEnd: "18:00", HolidaysFile: filepath.Join(t.TempDir(), "holidays.txt")}
+	os.WriteFile(policy.HolidaysFile, []byte("2024-07-04\n4 July\n"), 0o644)
+
+	if err := policy.validate(); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("validate = %v, want an error on line 2", err)
+	}
+}
+
+// alwaysHolidayPolicy defers everything but SEV1 today, on a holiday, and
+// returns its holidays file.
+func alwaysHolidayPolicy(t *testing.T) (Config, string) {
+	t.Helper()
+
+	holidays := filepath.Join(t.TempDir(), "holidays.txt")
+	today := time.Now()
+	dates := strings.Join([]string{today.AddDate(0, 0, -1).Format(time.DateOnly), today.Format(time.DateOnly), today.AddDate(0, 0, 1).Format(time.DateOnly)}, "\n")
+	if err := os.WriteFile(holidays, []byte(dates+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{NotificationPolicies: []NotificationPolicy{{
+		Channel:       "*",
+		BusinessStart: "00:00",
+		BusinessEnd:   "23:59",
+		BusinessDays:  []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+		HolidaysFile:  holidays,
+		Holidays:      "SEV2",
+	}}}
+	cfg.Slack.NewRelic = []AlertCondition{{ID: 7, Name: "CPU high"}}
+	return cfg, holidays
+}
+
+func TestDeferredIncidentSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	cfg, _ := alwaysHolidayPolicy(t)
+	store, err := NewConfigStore(cfg)
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	repo := NewMemoryRepository()
+
+	low := testPayload("42", "open")
+	low.Severity = "low"
+	resolved := testPayload("43", "open")
+	resolved.Severity = "low"
+
+	u := New(repo, WithConfig(store))
+	u.ProcessIncident(ctx, low)
+	u.ProcessIncident(ctx, resolved)
+	if calls := repo.Calls("SendMessage"); len(calls) != 0 {
+		t.Fatalf("deferred incidents were posted: %+v", calls)
+	}
+
+	// A restarted process still keeps the incidents quiet
+	restarted := New(repo, WithConfig(store))
+	low.State = "acknowledged"
+	incident, err := restarted.ProcessIncident(ctx, low)
+	if err != nil || incident.Status != "acknowledged" || incident.MessageTimestamp != "" {
+		t.Fatalf("ProcessIncident after restart = %+v, %v", incident, err)
+	}
+	resolved.State = "closed"
+	restarted.ProcessIncident(ctx, resolved)
+	if calls := repo.Calls("SendMessage"); len(calls) != 0 {
+		t.Fatalf("deferred incidents were posted after restart: %+v", calls)
+	}
+
+	restarted.SendDeferredSummaries(ctx)
+	if calls := repo.Calls("SendMessage"); len(calls) != 0 {
+		t.Fatalf("summary sent on a holiday: %+v", calls)
+	}
+
+	// Back in business hours the summary lists both and posts the unresolved one
+	store.Load().NotificationPolicies[0].holidays = map[string]string{}
+	restarted.SendDeferredSummaries(ctx)
+	sent := repo.Calls("SendMessage")
+	if len(sent) != 2 || !strings.Contains(sent[0].Text, "2 incident(s) deferred") {
+		t.Fatalf("SendMessage calls = %+v", sent)
+	}
+	for _, id := range []string{"42", "43"} {
+		incident, _ := repo.GetNewRelicIncidentByID(ctx, id, "C1")
+		if incident.MessageTimestamp == "" {
+			t.Errorf("incident %s still has no message after the summary", id)
+		}
+	}
+
+	// Nothing is deferred anymore, so the next run sends nothing
+	restarted.SendDeferredSummaries(ctx)
+	if n := len(repo.Calls("SendMessage")); n != 2 {
+		t.Errorf("second summary run sent %d messages", n-2)
+	}
+}
+
+func TestDeferredSummaryIsAttemptedOnce(t *testing.T) {
+	ctx := context.Background()
+	cfg, _ := alwaysHolidayPolicy(t)
+	store, err := NewConfigStore(cfg)
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	repo := NewMemoryRepository()
+	u := New(repo, WithConfig(store))
+
+	low := testPayload("42", "open")
+	low.Severity = "low"
+	u.ProcessIncident(ctx, low)
+
+	// Unposted because Slack was down, not because it was deferred
+	repo.Fail("SendMessage", errSlackDown)
+	u.ProcessIncident(ctx, testPayload("44", "open"))
+
+	// Back in business hours, the summary fails once and is not retried
+	store.Load().NotificationPolicies[0].holidays = map[string]string{}
+	u.SendDeferredSummaries(ctx)
+	repo.Fail("SendMessage", nil)
+	u.SendDeferredSummaries(ctx)
+	if calls := repo.Calls("SendMessage"); len(calls) != 0 {
+		t.Errorf("summary retried: %+v", calls)
+	}
+	if !u.hasEvent(ctx, "42", "C1", EventSummarized) {
+		t.Error("summarized event missing after the summary attempt")
+	}
+	if u.hasEvent(ctx, "44", "C1", EventSummarized) {
+		t.Error("incident that was never deferred was summarized")
+	}
+
+	// Its next event posts it like any other incident
+	low.State = "acknowledged"
+	if incident, err := u.ProcessIncident(ctx, low); err != nil || incident.MessageTimestamp == "" {
+		t.Errorf("ProcessIncident after the summary = %+v, %v; want it posted", incident, err)
+	}
+}
+
+func TestWatchReloadsHolidays(t *testing.T) {
+	_, holidays := alwaysHolidayPolicy(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	body := `{"slack": {"newrelic": [{"alert_condition_id": 7, "alert_condition_name": "CPU high"}]},
+		"notification_policies": [{"channel": "*", "business_start": "00:00", "business_end": "23:59",
+		"business_days": ["mon", "tue", "wed", "thu", "fri", "sat", "sun"], "holidays_file": "` + holidays + `", "holidays": "SEV2"}]}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+	store, err := NewConfigStore(*loaded)
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, path, 10*time.Millisecond)
+
+	policy, _ := store.Load().Policy("C1")
+	if policy.Period(time.Now()) != PeriodHoliday {
+		t.Fatal("today is not a holiday")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(holidays, []byte("# no holidays\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(holidays, later, later)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if policy, _ := store.Load().Policy("C1"); policy.Period(time.Now()) != PeriodHoliday {
+			return
+		}
+	}
+	t.Error("holidays file change was not reloaded")
+}
@@ -0,0 +1,271 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// threadRepository is implemented by repositories that can read back Slack thread replies.
+type threadRepository interface {
+	GetThreadReplies(ctx context.Context, channel, messageTimestamp string) ([]slack.Message, error)
+}
+
+// Postmortem holds everything known about an incident that goes into a postmortem draft.
+type Postmortem struct {
+	Incident      entitySlack.Incident
+	Timeline      []IncidentEvent
+	Replies       []slack.Message
+	ActionItems   []ActionItem
+	RootCause     string
+	Responders    []string
+	AckedAt       time.Time
+	TimeToAck     time.Duration
+	TimeToRecover time.Duration
+}
+
+// BuildPostmortem collects the incident record, its timeline and thread replies.
+func (u *UseCase) BuildPostmortem(ctx context.Context, incidentID, channel string) (Postmortem, error) {
+	incident, err := u.slackRepo.GetNewRelicIncident(ctx, incidentID, channel)
+	if err != nil {
+		return Postmortem{}, err
+	}
+
+	timeline, err := u.GetIncidentTimeline(ctx, incidentID, channel)
+	if err != nil {
+		return Postmortem{}, err
+	}
+
+	pm := Postmortem{
+		Incident:  incident,
+		Timeline:  timeline,
+		RootCause: incident.RootCause,
+	}
+
+	if threads, ok := u.baseRepo.(threadRepository); ok && incident.MessageTimestamp != "" {
+		replies, err := threads.GetThreadReplies(ctx, channel, incident.MessageTimestamp)
+		if err != nil {
+			log.Errorf("Error GET thread replies from slack: %s", err)
+		}
+		pm.Replies = replies
+	}
+
+	pm.ActionItems, err = u.actionItems.GetActionItemsByIncident(ctx, incidentID, channel)
+	if err != nil {
+		log.Errorf("Error GET action items on database: %s", err)
+	}
+	for i := range pm.ActionItems {
+		if pm.ActionItems[i].Owner != "" {
+			pm.ActionItems[i].Owner = u.displayName(ctx, pm.ActionItems[i].Owner)
+		}
+	}
+
+	responders := map[string]bool{}
+	for _, event := range timeline {
+		switch event.Type {
+		case EventAcknowledged:
+			if pm.AckedAt.IsZero() {
+				pm.AckedAt = event.CreatedAt
+			}
+			responders[event.Actor] = true
+		case EventRootCause:
+			pm.RootCause = event.Detail
+			responders[event.Actor] = true
+		}
+	}
+	for _, reply := range pm.Replies {
+		if reply.User != "" {
+			responders[reply.User] = true
+		}
+	}
+	for responder := range responders {
+		if responder != "" {
+			pm.Responders = append(pm.Responders, u.displayName(ctx, responder))
+		}
+	}
+	sort.Strings(pm.Responders)
+
+	// The draft is plain Markdown: show names instead of Slack user IDs
+	pm.Timeline = make([]IncidentEvent, len(timeline))
+	for i, event := range timeline {
+		event.Actor = u.displayName(ctx, event.Actor)
+		event.Detail = u.plainMentions(ctx, event.Detail)
+		pm.Timeline[i] = event
+	}
+	for i := range pm.Replies {
+		pm.Replies[i].User = u.displayName(ctx, pm.Replies[i].User)
+		pm.Replies[i].Text = u.plainMentions(ctx, pm.Replies[i].Text)
+	}
+
+	if !pm.AckedAt.IsZero() && !incident.StartTime.IsZero() {
+		pm.TimeToAck = pm.AckedAt.Sub(incident.StartTime)
+	}
+	if !incident.RecoverTime.IsZero() && !incident.StartTime.IsZero() {
+		pm.TimeToRecover = incident.RecoverTime.Sub(incident.StartTime)
+	}
+
+	return pm, nil
+}
+
+// GeneratePostmortem renders a Markdown postmortem draft for the incident.
+func (u *UseCase) GeneratePostmortem(ctx context.Context, incidentID, channel string) (string, error) {
+	pm, err := u.BuildPostmortem(ctx, incidentID, channel)
+	if err != nil {
+		return "", err
+	}
+
+	return pm.Markdown(), nil
+}
+
+// Markdown renders the postmortem draft, ready to paste into the wiki.
+func (p Postmortem) Markdown() string {
+	i := p.Incident
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# Postmortem: %s\n\n", i.Name)
+
+	b.WriteString("## Summary\n\n")
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Incident | [%s](%s) |\n", incidentRef(i), i.URL)
+	fmt.Fprintf(&b, "| Source | %s |\n", i.GeneratedBy)
+	fmt.Fprintf(&b, "| Severity | %s |\n", orPlaceholder(i.Severity))
+	fmt.Fprintf(&b, "| Owner | %s |\n", orPlaceholder(i.Owner))
+	fmt.Fprintf(&b, "| Current status | `%s` |\n", i.Status)
+	fmt.Fprintf(&b, "| Started | %s |\n", formatTime(i.StartTime))
+	fmt.Fprintf(&b, "| Recovered | %s |\n", formatTime(i.RecoverTime))
+	fmt.Fprintf(&b, "| Impact duration | %s |\n", formatDuration(p.impact()))
+	fmt.Fprintf(&b, "| Time to acknowledge | %s |\n", formatDuration(p.TimeToAck))
+	fmt.Fprintf(&b, "| Time to resolve | %s |\n\n", formatDuration(p.TimeToRecover))
+	fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(i.Description))
+
+	b.WriteString("## Timeline\n\n")
+	if len(p.Timeline) == 0 {
+		b.WriteString("_No events recorded._\n")
+	}
+	for _, event := range p.Timeline {
+		fmt.Fprintf(&b, "- **%s** %s\n", event.CreatedAt.Format(time.RFC1123), describeEvent(event))
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Root Cause\n\n")
+	fmt.Fprintf(&b, "%s\n\n", orPlaceholder(strings.Replace(p.RootCause, "-", " ", -1)))
+
+	b.WriteString("## Responders\n\n")
+	if len(p.Responders) == 0 {
+		b.WriteString("- _TBD_\n")
+	}
+	for _, responder := range p.Responders {
+		fmt.Fprintf(&b, "- %s\n", responder)
+	}
+	b.WriteString("\n")
+
+	if len(p.Replies) > 0 {
+		b.WriteString("## Discussion\n\n")
+		for _, reply := range p.Replies {
+			text := strings.Replace(strings.TrimSpace(reply.Text), "\n", " ", -1)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "- %s: %s\n", reply.User, text)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Action Items\n\n")
+	b.WriteString("| Action | Owner | Due date | Status |\n|---|---|---|---|\n")
+	if len(p.ActionItems) == 0 {
+		b.WriteString("| _TBD_ | _TBD_ | _TBD_ | open |\n")
+	}
+	for _, item := range p.ActionItems {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", item.Description, orPlaceholder(item.Owner), formatDate(item.DueDate), item.Status)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("## Lessons Learned\n\n- _What went well?_\n- _What went wrong?_\n- _Where did we get lucky?_\n")
+
+	return b.String()
+}
+
+func (p Postmortem) impact() time.Duration {
+	if p.TimeToRecover > 0 {
+		return p.TimeToRecover
+	}
+	if p.Incident.StartTime.IsZero() {
+		return 0
+	}
+
+	return time.Since(p.Incident.StartTime)
+}
+
+func describeEvent(event IncidentEvent) string {
+	switch event.Type {
+	case EventReceived:
+		if event.Stale {
+			return fmt.Sprintf("%s notification received out of order (`%s`), ignored", event.Actor, event.State)
+		}
+		return fmt.Sprintf("%s notification received (`%s`)", event.Actor, event.State)
+	case EventStateChanged:
+		if event.PreviousState == "" {
+			return fmt.Sprintf("incident opened as `%s`", event.State)
+		}
+		return fmt.Sprintf("status changed `%s` → `%s`", event.PreviousState, event.State)
+	case EventAcknowledged:
+		return fmt.Sprintf("acknowledged by %s", event.Actor)
+	case EventRootCause:
+		return fmt.Sprintf("root cause set to \"%s\" by %s", event.Detail, event.Actor)
+	case EventLinked:
+		return fmt.Sprintf("linked by %s: %s", orPlaceholder(event.Actor), event.Detail)
+	case EventEscalated:
+		return fmt.Sprintf("escalated by %s: %s", event.Actor, event.Detail)
+	case EventAssigned:
+		return fmt.Sprintf("assigned to %s by %s", event.Detail, orPlaceholder(event.Actor))
+	case EventDeferred:
+		return fmt.Sprintf("held back until the next summary (%s)", event.Detail)
+	case EventSummarized:
+		if event.Detail != "" {
+			return fmt.Sprintf("released by the deferred summary (%s)", event.Detail)
+		}
+		return "released by the deferred summary"
+	}
+
+	return fmt.Sprintf("%s %s", event.Type, event.Detail)
+}
+
+func incidentRef(i entitySlack.Incident) string {
+	if i.IncidentID != "" {
+		return i.IncidentID
+	}
+
+	return i.Name
+}
+
+func orPlaceholder(s string) string {
+	if s == "" || s == "null" {
+		return "_TBD_"
+	}
+
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format(time.RFC1123)
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+
+	return d.Round(time.Second).String()
+}
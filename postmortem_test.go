@@ -0,0 +1,21 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostmortemActionItemPlaceholder(t *testing.T) {
+	empty := Postmortem{}.Markdown()
+	if !strings.Contains(empty, "| _TBD_ | _TBD_ | _TBD_ | open |") {
+		t.Errorf("postmortem without action items has no placeholder row:\n%s", empty)
+	}
+
+	withItems := Postmortem{ActionItems: []ActionItem{{Description: "Add disk alerts", Owner: "team-a", Status: "open"}}}.Markdown()
+	if strings.Contains(withItems, "| _TBD_ | _TBD_ | _TBD_ |") {
+		t.Errorf("postmortem with action items still has the placeholder row:\n%s", withItems)
+	}
+	if !strings.Contains(withItems, "| Add disk alerts | team-a |") {
+		t.Errorf("action item row missing:\n%s", withItems)
+	}
+}
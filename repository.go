@@ -0,0 +1,62 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+// slackRepository is the storage and Slack API dependency of UseCase.
+type slackRepository interface {
+	GetNewRelicIncident(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error)
+	GetNewRelicIncidentByID(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error)
+	GetNewRelicIncidentByMsgTimestamp(ctx context.Context, incidentID, messageTimestamp string) (entitySlack.Incident, error)
+	InsertNewRelicIncident(ctx context.Context, incidentID string, conditionID int, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels string, startTime, recoverTime time.Time) error
+	UpdateNewRelicIncidentStatusByID(ctx context.Context, status, messageTimestamp, channel, incidentID string, recoverTime time.Time) error
+	// UpdateNewRelicIncidentByID sets the root cause of the incident in
+	// every channel it was posted to.
+	UpdateNewRelicIncidentByID(ctx context.Context, rootCause, incidentID string) error
+	InsertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID string) error
+	UpdateMessageByTimestamp(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, channel string) error
+	GetMessageByTimestamp(ctx context.Context, messageTimestamp, channel string) (entitySlack.Message, error)
+	SendMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error)
+	UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error)
+	ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error)
+	SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error)
+	ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error)
+}
+
+// Optional repository capabilities the usecase checks its base repository for.
+type (
+	// channelMessageRepository stores messages keyed by channel as well as
+	// timestamp, so timestamps may repeat across channels.
+	channelMessageRepository interface {
+		InsertChannelMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID, channel string) error
+	}
+	// ackFormRepository opens the Ack form listing options as root causes
+	// with formBlocks, extra optional inputs, rendered below them. Their
+	// values come back in the view state of the submission.
+	ackFormRepository interface {
+		SubmitButtonActionWithForm(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool, formBlocks ...slack.Block) (string, error)
+	}
+)
+
+// insertMessage stores a posted message under its channel when the
+// repository keys messages by channel, and by timestamp alone otherwise.
+func (u *UseCase) insertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID, channel string) error {
+	if messages, ok := u.baseRepo.(channelMessageRepository); ok {
+		return messages.InsertChannelMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID, channel)
+	}
+
+	return u.slackRepo.InsertMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID)
+}
+
+// Repository is slackRepository for implementations outside the package,
+// such as the SQL repositories.
+type Repository = slackRepository
+
+// ErrNotFound is returned by repositories when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
@@ -0,0 +1,733 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+type (
+	Repository    = usecaseSlack.Repository
+	IncidentQuery = usecaseSlack.IncidentQuery
+)
+
+var ErrNotFound = usecaseSlack.ErrNotFound
+
+type incidentSearcher interface {
+	SearchIncidents(ctx context.Context, query IncidentQuery) ([]entitySlack.Incident, int, error)
+}
+
+type incidentDeleter interface {
+	DeleteNewRelicIncident(ctx context.Context, incidentID, channel string) error
+}
+
+type channelMessageInserter interface {
+	InsertChannelMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID, channel string) error
+}
+
+type severityUpdater interface {
+	UpdateNewRelicIncidentSeverityByID(ctx context.Context, severity, incidentID, channel string) error
+}
+
+type customerFacingStore interface {
+	InsertCustomerFacing(ctx context.Context, incidentID, channel, actor, summary string) error
+	IsCustomerFacing(ctx context.Context, incidentID, channel string) (bool, error)
+	GetCustomerFacingSummary(ctx context.Context, incidentID, channel string) (string, error)
+	DeleteCustomerFacing(ctx context.Context, incidentID, channel string) error
+}
+
+type actionItemStore interface {
+	InsertActionItem(ctx context.Context, item usecaseSlack.ActionItem) (usecaseSlack.ActionItem, error)
+	UpdateActionItem(ctx context.Context, item usecaseSlack.ActionItem) error
+	GetActionItem(ctx context.Context, id string) (usecaseSlack.ActionItem, error)
+	GetActionItemsByIncident(ctx context.Context, incidentID, channel string) ([]usecaseSlack.ActionItem, error)
+	GetOpenActionItems(ctx context.Context) ([]usecaseSlack.ActionItem, error)
+	DeleteActionItems(ctx context.Context, incidentID, channel string) error
+}
+
+type linkStore interface {
+	InsertIncidentLink(ctx context.Context, link usecaseSlack.IncidentLink) error
+	GetIncidentLinks(ctx context.Context, incidentID, channel string) ([]usecaseSlack.IncidentLink, error)
+	GetChildLinks(ctx context.Context, parentID, parentChannel string) ([]usecaseSlack.IncidentLink, error)
+	DeleteIncidentLinks(ctx context.Context, incidentID, channel string) error
+}
+
+type ticketStore interface {
+	InsertTicket(ctx context.Context, incidentID, channel string, ticket usecaseSlack.Ticket) error
+	GetTicket(ctx context.Context, incidentID, channel string) (usecaseSlack.Ticket, error)
+	DeleteTicket(ctx context.Context, incidentID, channel string) error
+}
+
+type assignmentStore interface {
+	InsertAssignment(ctx context.Context, assignment usecaseSlack.Assignment) error
+	GetAssignments(ctx context.Context, incidentID, channel string) ([]usecaseSlack.Assignment, error)
+	DeleteAssignments(ctx context.Context, incidentID, channel string) error
+}
+
+type eventLog interface {
+	InsertIncidentEvent(ctx context.Context, event usecaseSlack.IncidentEvent) error
+	GetIncidentEvents(ctx context.Context, incidentID, channel string) ([]usecaseSlack.IncidentEvent, error)
+	DeleteIncidentEvents(ctx context.Context, incidentID, channel string) error
+}
+
+func TestMemoryRepository(t *testing.T) {
+	runRepositoryConformance(t, func(t *testing.T) Repository {
+		return usecaseSlack.NewMemoryRepository()
+	})
+}
+
+func TestMemoryEventLog(t *testing.T) {
+	runEventLogConformance(t, func(t *testing.T) eventLog {
+		return usecaseSlack.NewMemoryEventLog()
+	})
+}
+
+func TestMemoryCustomerFacing(t *testing.T) {
+	runCustomerFacingConformance(t, usecaseSlack.NewMemoryCustomerFacing())
+}
+
+func TestMemoryActionItems(t *testing.T) {
+	runActionItemConformance(t, usecaseSlack.NewMemoryActionItems())
+}
+
+func TestMemoryLinks(t *testing.T) {
+	runLinkConformance(t, usecaseSlack.NewMemoryLinks())
+}
+
+func TestMemoryTickets(t *testing.T) {
+	runTicketConformance(t, usecaseSlack.NewMemoryTickets())
+}
+
+func TestMemoryAssignments(t *testing.T) {
+	runAssignmentConformance(t, usecaseSlack.NewMemoryAssignments())
+}
+
+// runRepositoryConformance checks the storage contract UseCase relies on
+// against repositories returned empty by newRepository. Messages are posted
+// through the repository's own SendMessage first, so the Slack half must
+// work against a fake such as FakeSlack.
+func runRepositoryConformance(t *testing.T, newRepository func(t *testing.T) Repository) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	insert := func(t *testing.T, repo Repository, incidentID, channel string, startTime time.Time) {
+		t.Helper()
+		err := repo.InsertNewRelicIncident(context.Background(), incidentID, 7, "CPU high", "https://newrelic/"+incidentID, "cpu > 90%", "team-a", "newrelic", "open", "critical", "null", channel, `{"env":"prod"}`, startTime, time.Time{})
+		if err != nil {
+			t.Fatalf("InsertNewRelicIncident(%s, %s): %v", incidentID, channel, err)
+		}
+	}
+
+	insertMessage := func(t *testing.T, repo Repository, ts, incidentID, channel string) error {
+		t.Helper()
+		messages, ok := repo.(channelMessageInserter)
+		if !ok {
+			t.Fatalf("%T does not store messages by channel", repo)
+		}
+		return messages.InsertChannelMessage(context.Background(), "", "", "", ts, incidentID, channel)
+	}
+
+	post := func(t *testing.T, repo Repository, incidentID, channel string) string {
+		t.Helper()
+		_, ts, err := repo.SendMessage(context.Background(), channel, "incident "+incidentID, "FF0000", "", "newrelic", "")
+		if err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+		if err := insertMessage(t, repo, ts, incidentID, channel); err != nil {
+			t.Fatalf("InsertMessage(%s): %v", ts, err)
+		}
+		return ts
+	}
+
+	t.Run("InsertAndGetIncident", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+
+		for name, get := range map[string]func(context.Context, string, string) (entitySlack.Incident, error){
+			"GetNewRelicIncident":     repo.GetNewRelicIncident,
+			"GetNewRelicIncidentByID": repo.GetNewRelicIncidentByID,
+		} {
+			got, err := get(ctx, "1", "C1")
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if got.IncidentID != "1" || got.Channel != "C1" || got.ConditionID != 7 || got.Name != "CPU high" ||
+				got.URL != "https://newrelic/1" || got.Description != "cpu > 90%" || got.Owner != "team-a" ||
+				got.GeneratedBy != "newrelic" || got.Status != "open" || got.Severity != "critical" ||
+				got.RootCause != "null" || got.Labels != `{"env":"prod"}` {
+				t.Errorf("%s = %+v", name, got)
+			}
+			if !got.StartTime.Equal(start) || !got.RecoverTime.IsZero() {
+				t.Errorf("%s times = %v, %v; want %v, zero", name, got.StartTime, got.RecoverTime, start)
+			}
+			if got.MessageTimestamp != "" {
+				t.Errorf("%s message timestamp = %q before any message", name, got.MessageTimestamp)
+			}
+		}
+	})
+
+	t.Run("UpdateSeverity", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		updater, ok := repo.(severityUpdater)
+		if !ok {
+			t.Skip("repository cannot update severities")
+		}
+		insert(t, repo, "1", "C1", start)
+		insert(t, repo, "1", "C2", start)
+
+		if err := updater.UpdateNewRelicIncidentSeverityByID(ctx, "SEV1", "1", "C1"); err != nil {
+			t.Fatalf("UpdateNewRelicIncidentSeverityByID: %v", err)
+		}
+		if got, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C1"); got.Severity != "SEV1" {
+			t.Errorf("C1 severity = %q, want SEV1", got.Severity)
+		}
+		if got, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C2"); got.Severity != "critical" {
+			t.Errorf("C2 severity = %q, want it unchanged", got.Severity)
+		}
+		if err := updater.UpdateNewRelicIncidentSeverityByID(ctx, "SEV1", "404", "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateNewRelicIncidentSeverityByID(404) = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DuplicateIncident", func(t *testing.T) {
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+
+		err := repo.InsertNewRelicIncident(context.Background(), "1", 7, "", "", "", "", "", "open", "", "null", "C1", "", start, time.Time{})
+		if err == nil {
+			t.Error("second insert of the same incident and channel succeeded")
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+
+		if _, err := repo.GetNewRelicIncidentByID(ctx, "404", "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetNewRelicIncidentByID = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetMessageByTimestamp(ctx, "1.000001", "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetMessageByTimestamp = %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdateNewRelicIncidentStatusByID(ctx, "closed", "", "C1", "404", time.Now()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateNewRelicIncidentStatusByID = %v, want ErrNotFound", err)
+		}
+		if err := repo.UpdateNewRelicIncidentByID(ctx, "db-down", "404"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("UpdateNewRelicIncidentByID = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+		ts := post(t, repo, "1", "C1")
+
+		message, err := repo.GetMessageByTimestamp(ctx, ts, "C1")
+		if err != nil || message.IncidentID != "1" || message.MessageTimestamp != ts {
+			t.Fatalf("GetMessageByTimestamp = %+v, %v", message, err)
+		}
+
+		if err := repo.UpdateMessageByTimestamp(ctx, "trigger", "workspace", "U0ALICE01", ts, "C1"); err != nil {
+			t.Fatalf("UpdateMessageByTimestamp: %v", err)
+		}
+		message, _ = repo.GetMessageByTimestamp(ctx, ts, "C1")
+		if message.TriggerID != "trigger" || message.Workspace != "workspace" || message.UserACK != "U0ALICE01" {
+			t.Errorf("message after update = %+v", message)
+		}
+
+		incident, err := repo.GetNewRelicIncidentByMsgTimestamp(ctx, "1", ts)
+		if err != nil || incident.Channel != "C1" || incident.MessageTimestamp != ts || incident.UserACK != "U0ALICE01" {
+			t.Errorf("GetNewRelicIncidentByMsgTimestamp = %+v, %v", incident, err)
+		}
+
+		if err := insertMessage(t, repo, ts, "1", "C1"); err == nil {
+			t.Error("second insert of the same message timestamp succeeded")
+		}
+	})
+
+	t.Run("SameTimestampInTwoChannels", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+		insert(t, repo, "2", "C2", start)
+
+		// Slack timestamps are only unique within a channel
+		const ts = "1700000000.000100"
+		if err := insertMessage(t, repo, ts, "1", "C1"); err != nil {
+			t.Fatalf("InsertMessage(C1): %v", err)
+		}
+		if err := insertMessage(t, repo, ts, "2", "C2"); err != nil {
+			t.Fatalf("InsertMessage(C2): %v", err)
+		}
+
+		for channel, incidentID := range map[string]string{"C1": "1", "C2": "2"} {
+			if message, err := repo.GetMessageByTimestamp(ctx, ts, channel); err != nil || message.IncidentID != incidentID {
+				t.Errorf("GetMessageByTimestamp(%s) = %+v, %v; want incident %s", channel, message, err, incidentID)
+			}
+		}
+		if incident, err := repo.GetNewRelicIncidentByMsgTimestamp(ctx, "2", ts); err != nil || incident.Channel != "C2" {
+			t.Errorf("GetNewRelicIncidentByMsgTimestamp(2) = %+v, %v; want C2", incident, err)
+		}
+	})
+
+	t.Run("MessagesWithoutChannel", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+		insert(t, repo, "1", "C2", start.Add(time.Minute))
+
+		// Callers that do not know the channel post to the latest incident's
+		if err := repo.InsertMessage(ctx, "", "", "", "1700000000.000100", "1"); err != nil {
+			t.Fatalf("InsertMessage: %v", err)
+		}
+		c1, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C1")
+		c2, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C2")
+		if c1.MessageTimestamp != "" || c2.MessageTimestamp != "1700000000.000100" {
+			t.Errorf("message timestamps = %q, %q; want it on C2", c1.MessageTimestamp, c2.MessageTimestamp)
+		}
+	})
+
+	t.Run("ChannelsAreIsolated", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		insert(t, repo, "1", "C1", start)
+		ts1 := post(t, repo, "1", "C1")
+		insert(t, repo, "1", "C2", start.Add(time.Minute))
+		ts2 := post(t, repo, "1", "C2")
+
+		recoverTime := start.Add(30 * time.Minute)
+		if err := repo.UpdateNewRelicIncidentStatusByID(ctx, "closed", ts1, "C1", "1", recoverTime); err != nil {
+			t.Fatalf("UpdateNewRelicIncidentStatusByID: %v", err)
+		}
+
+		c1, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C1")
+		c2, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C2")
+		if c1.Status != "closed" || !c1.RecoverTime.Equal(recoverTime) || c1.MessageTimestamp != ts1 {
+			t.Errorf("C1 = %+v", c1)
+		}
+		if c2.Status != "open" || !c2.RecoverTime.IsZero() || c2.MessageTimestamp != ts2 {
+			t.Errorf("C2 = %+v", c2)
+		}
+
+		// Root causes are set per incident ID, across channels
+		if err := repo.UpdateNewRelicIncidentByID(ctx, "db-down", "1"); err != nil {
+			t.Fatalf("UpdateNewRelicIncidentByID: %v", err)
+		}
+		c1, _ = repo.GetNewRelicIncidentByID(ctx, "1", "C1")
+		c2, _ = repo.GetNewRelicIncidentByID(ctx, "1", "C2")
+		if c1.RootCause != "db-down" || c2.RootCause != "db-down" {
+			t.Errorf("root causes = %q, %q", c1.RootCause, c2.RootCause)
+		}
+
+		// Reopening clears the recover time
+		if err := repo.UpdateNewRelicIncidentStatusByID(ctx, "open", ts1, "C1", "1", time.Time{}); err != nil {
+			t.Fatalf("UpdateNewRelicIncidentStatusByID: %v", err)
+		}
+		c1, _ = repo.GetNewRelicIncidentByID(ctx, "1", "C1")
+		if c1.Status != "open" || !c1.RecoverTime.IsZero() {
+			t.Errorf("reopened C1 = %+v", c1)
+		}
+	})
+
+	t.Run("SearchIncidents", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		searcher, ok := repo.(incidentSearcher)
+		if !ok {
+			t.Skip("repository does not implement SearchIncidents")
+		}
+
+		for i, id := range []string{"1", "2", "3", "4"} {
+			insert(t, repo, id, "C1", start.Add(time.Duration(i)*time.Minute))
+		}
+		if err := repo.UpdateNewRelicIncidentStatusByID(ctx, "closed", "", "C1", "2", start.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		all, total, err := searcher.SearchIncidents(ctx, IncidentQuery{Limit: 2, Offset: 1})
+		if err != nil || total != 4 || len(all) != 2 || all[0].IncidentID != "3" || all[1].IncidentID != "2" {
+			t.Errorf("page 2 = %v, %d, %v; want [3 2] of 4", incidentIDs(all), total, err)
+		}
+
+		closed, total, err := searcher.SearchIncidents(ctx, IncidentQuery{Status: []string{"CLOSED"}, Severity: "Critical"})
+		if err != nil || total != 1 || len(closed) != 1 || closed[0].IncidentID != "2" {
+			t.Errorf("closed = %v, %d, %v; want [2]", incidentIDs(closed), total, err)
+		}
+
+		window, total, err := searcher.SearchIncidents(ctx, IncidentQuery{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)})
+		if err != nil || total != 2 || len(window) != 2 || window[0].IncidentID != "3" {
+			t.Errorf("window = %v, %d, %v; want [3 2]", incidentIDs(window), total, err)
+		}
+
+		labelled, total, err := searcher.SearchIncidents(ctx, IncidentQuery{Labels: map[string]string{"env": "prod"}, Limit: 3})
+		if err != nil || total != 4 || len(labelled) != 3 {
+			t.Errorf("labelled = %v, %d, %v; want 3 of 4", incidentIDs(labelled), total, err)
+		}
+	})
+
+	t.Run("SearchNormalizedSeverity", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		searcher, ok := repo.(incidentSearcher)
+		if !ok {
+			t.Skip("repository does not implement SearchIncidents")
+		}
+
+		for i, severity := range []string{"critical", "SEV1", "P2", "", "weird", "sev3"} {
+			id := strconv.Itoa(i + 1)
+			err := repo.InsertNewRelicIncident(ctx, id, 7, "CPU high", "", "", "", "newrelic", "open", severity, "null", "C1", "{}", start.Add(time.Duration(i)*time.Minute), time.Time{})
+			if err != nil {
+				t.Fatalf("InsertNewRelicIncident(%s): %v", id, err)
+			}
+		}
+
+		for severity, want := range map[string][]string{
+			"sev1":     {"2", "1"},
+			"Critical": {"2", "1"},
+			"high":     {"3"},
+			"SEV3":     {"6", "5", "4"},
+			"SEV4":     {},
+		} {
+			got, total, err := searcher.SearchIncidents(ctx, IncidentQuery{Severity: severity})
+			if err != nil || total != len(want) || strings.Join(incidentIDs(got), ",") != strings.Join(want, ",") {
+				t.Errorf("severity %s = %v, %d, %v; want %v", severity, incidentIDs(got), total, err, want)
+			}
+		}
+	})
+
+	t.Run("DeleteIncident", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		deleter, ok := repo.(incidentDeleter)
+		if !ok {
+			t.Skip("repository does not implement DeleteNewRelicIncident")
+		}
+
+		insert(t, repo, "1", "C1", start)
+		ts := post(t, repo, "1", "C1")
+		insert(t, repo, "1", "C2", start)
+
+		if err := deleter.DeleteNewRelicIncident(ctx, "1", "C1"); err != nil {
+			t.Fatalf("DeleteNewRelicIncident: %v", err)
+		}
+		if _, err := repo.GetNewRelicIncidentByID(ctx, "1", "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleted incident: %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetMessageByTimestamp(ctx, ts, "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleted message: %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetNewRelicIncidentByID(ctx, "1", "C2"); err != nil {
+			t.Errorf("other channel: %v", err)
+		}
+		if err := deleter.DeleteNewRelicIncident(ctx, "1", "C1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second delete = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func incidentIDs(incidents []entitySlack.Incident) []string {
+	ids := []string{}
+	for _, incident := range incidents {
+		ids = append(ids, incident.IncidentID)
+	}
+	return ids
+}
+
+// runEventLogConformance checks that event logs return every field they were
+// given, in insertion order, per incident and channel.
+func runEventLogConformance(t *testing.T, newEventLog func(t *testing.T) eventLog) {
+	ctx := context.Background()
+	events := newEventLog(t)
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	want := []usecaseSlack.IncidentEvent{
+		{IncidentID: "1", Channel: "C1", Type: usecaseSlack.EventReceived, State: "open", Actor: "newrelic",
+			Payload: []byte(`{"state":"open"}`), OccurredAt: createdAt.Add(-time.Second), CreatedAt: createdAt},
+		{IncidentID: "1", Channel: "C1", Type: usecaseSlack.EventStateChanged, State: "closed", PreviousState: "open",
+			Actor: "newrelic", CreatedAt: createdAt.Add(time.Minute)},
+		{IncidentID: "1", Channel: "C1", Type: usecaseSlack.EventReceived, State: "open", Stale: true,
+			OccurredAt: createdAt.Add(-time.Minute), CreatedAt: createdAt.Add(2 * time.Minute)},
+	}
+	for _, event := range want {
+		if err := events.InsertIncidentEvent(ctx, event); err != nil {
+			t.Fatalf("InsertIncidentEvent: %v", err)
+		}
+	}
+	other := usecaseSlack.IncidentEvent{IncidentID: "1", Channel: "C2", Type: usecaseSlack.EventRootCause, Detail: "db-down", CreatedAt: createdAt}
+	if err := events.InsertIncidentEvent(ctx, other); err != nil {
+		t.Fatalf("InsertIncidentEvent: %v", err)
+	}
+
+	got, err := events.GetIncidentEvents(ctx, "1", "C1")
+	if err != nil {
+		t.Fatalf("GetIncidentEvents: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetIncidentEvents(1, C1) returned %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.IncidentID != w.IncidentID || g.Channel != w.Channel || g.Type != w.Type || g.State != w.State ||
+			g.PreviousState != w.PreviousState || g.Actor != w.Actor || g.Detail != w.Detail || g.Stale != w.Stale ||
+			string(g.Payload) != string(w.Payload) || !g.OccurredAt.Equal(w.OccurredAt) || !g.CreatedAt.Equal(w.CreatedAt) {
+			t.Errorf("event %d = %+v, want %+v", i, g, w)
+		}
+	}
+
+	got, err = events.GetIncidentEvents(ctx, "1", "C2")
+	if err != nil || len(got) != 1 || got[0].Detail != "db-down" {
+		t.Errorf("GetIncidentEvents(1, C2) = %+v, %v", got, err)
+	}
+	got, err = events.GetIncidentEvents(ctx, "404", "C1")
+	if err != nil || len(got) != 0 {
+		t.Errorf("GetIncidentEvents(404, C1) = %+v, %v", got, err)
+	}
+
+	if err := events.DeleteIncidentEvents(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteIncidentEvents: %v", err)
+	}
+	if got, err = events.GetIncidentEvents(ctx, "1", "C1"); err != nil || len(got) != 0 {
+		t.Errorf("GetIncidentEvents(1, C1) after delete = %+v, %v", got, err)
+	}
+	if got, err = events.GetIncidentEvents(ctx, "1", "C2"); err != nil || len(got) != 1 {
+		t.Errorf("GetIncidentEvents(1, C2) after deleting C1 = %+v, %v", got, err)
+	}
+}
+
+// runCustomerFacingConformance checks that flags are per incident and
+// channel, that flagging twice is allowed and that flagging again replaces
+// the summary unless it is empty.
+func runCustomerFacingConformance(t *testing.T, store customerFacingStore) {
+	ctx := context.Background()
+
+	for i, summary := range []string{"Checkout is slow", "Checkout is down", ""} {
+		if err := store.InsertCustomerFacing(ctx, "1", "C1", "U0ALICE01", summary); err != nil {
+			t.Fatalf("InsertCustomerFacing #%d: %v", i+1, err)
+		}
+	}
+	if summary, err := store.GetCustomerFacingSummary(ctx, "1", "C1"); err != nil || summary != "Checkout is down" {
+		t.Errorf("GetCustomerFacingSummary(1, C1) = %q, %v; want the last non-empty summary", summary, err)
+	}
+	if _, err := store.GetCustomerFacingSummary(ctx, "1", "C2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCustomerFacingSummary(1, C2) = %v; want ErrNotFound", err)
+	}
+
+	for _, tc := range []struct {
+		incidentID, channel string
+		want                bool
+	}{
+		{"1", "C1", true},
+		{"1", "C2", false},
+		{"2", "C1", false},
+	} {
+		got, err := store.IsCustomerFacing(ctx, tc.incidentID, tc.channel)
+		if err != nil || got != tc.want {
+			t.Errorf("IsCustomerFacing(%s, %s) = %v, %v; want %v", tc.incidentID, tc.channel, got, err, tc.want)
+		}
+	}
+	if err := store.DeleteCustomerFacing(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteCustomerFacing: %v", err)
+	}
+	if got, err := store.IsCustomerFacing(ctx, "1", "C1"); err != nil || got {
+		t.Errorf("IsCustomerFacing(1, C1) after delete = %v, %v", got, err)
+	}
+}
+
+// runActionItemConformance checks that action items round-trip, are listed
+// per incident and channel, leave the open list once closed and are deleted
+// per incident and channel.
+func runActionItemConformance(t *testing.T, store actionItemStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	want := usecaseSlack.ActionItem{
+		IncidentID:  "1",
+		Channel:     "C1",
+		Team:        "payments",
+		Owner:       "U0ALICE01",
+		Description: "Add a disk usage alert",
+		Status:      usecaseSlack.ActionItemOpen,
+		DueDate:     now.Add(72 * time.Hour),
+		CreatedAt:   now,
+	}
+	first, err := store.InsertActionItem(ctx, want)
+	if err != nil {
+		t.Fatalf("InsertActionItem: %v", err)
+	}
+	if first.ID == "" {
+		t.Fatal("InsertActionItem returned no ID")
+	}
+	other, err := store.InsertActionItem(ctx, usecaseSlack.ActionItem{
+		IncidentID: "1", Channel: "C2", Description: "Page the DBA", Status: usecaseSlack.ActionItemOpen, CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("InsertActionItem(C2): %v", err)
+	}
+
+	got, err := store.GetActionItem(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetActionItem: %v", err)
+	}
+	want.ID = first.ID
+	if got.ID != want.ID || got.Owner != want.Owner || got.Team != want.Team || got.Description != want.Description ||
+		got.Status != want.Status || !got.DueDate.Equal(want.DueDate) || !got.CreatedAt.Equal(want.CreatedAt) || !got.RemindedAt.IsZero() {
+		t.Errorf("GetActionItem = %+v; want %+v", got, want)
+	}
+	if _, err := store.GetActionItem(ctx, "999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetActionItem(999) = %v; want ErrNotFound", err)
+	}
+
+	items, err := store.GetActionItemsByIncident(ctx, "1", "C1")
+	if err != nil || len(items) != 1 || items[0].ID != first.ID {
+		t.Errorf("GetActionItemsByIncident(1, C1) = %+v, %v; want only #%s", items, err, first.ID)
+	}
+
+	got.Status = usecaseSlack.ActionItemDone
+	got.RemindedAt = now.Add(time.Hour)
+	if err := store.UpdateActionItem(ctx, got); err != nil {
+		t.Fatalf("UpdateActionItem: %v", err)
+	}
+	if got, err = store.GetActionItem(ctx, first.ID); err != nil || got.Status != usecaseSlack.ActionItemDone || !got.RemindedAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("GetActionItem after update = %+v, %v", got, err)
+	}
+	if err := store.UpdateActionItem(ctx, usecaseSlack.ActionItem{ID: "999"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateActionItem(999) = %v; want ErrNotFound", err)
+	}
+
+	open, err := store.GetOpenActionItems(ctx)
+	if err != nil || len(open) != 1 || open[0].ID != other.ID {
+		t.Errorf("GetOpenActionItems = %+v, %v; want only #%s", open, err, other.ID)
+	}
+
+	if err := store.DeleteActionItems(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteActionItems: %v", err)
+	}
+	if items, err = store.GetActionItemsByIncident(ctx, "1", "C1"); err != nil || len(items) != 0 {
+		t.Errorf("GetActionItemsByIncident(1, C1) after delete = %+v, %v", items, err)
+	}
+	if items, err = store.GetActionItemsByIncident(ctx, "1", "C2"); err != nil || len(items) != 1 {
+		t.Errorf("GetActionItemsByIncident(1, C2) after deleting C1 = %+v, %v", items, err)
+	}
+}
+
+// runLinkConformance checks that links are found from both ends, that
+// linking again replaces the link and that deleting an incident removes the
+// links it is either end of.
+func runLinkConformance(t *testing.T, store linkStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	for _, link := range []usecaseSlack.IncidentLink{
+		{IncidentID: "2", Channel: "C1", Type: usecaseSlack.LinkRelated, ParentID: "1", ParentChannel: "C1", CreatedBy: "U0ALICE01", CreatedAt: now},
+		{IncidentID: "2", Channel: "C1", Type: usecaseSlack.LinkCausedBy, ParentID: "1", ParentChannel: "C1", CreatedBy: "U0BOB0001", CreatedAt: now.Add(time.Second)},
+		{IncidentID: "3", Channel: "C2", Type: usecaseSlack.LinkDuplicateOf, ParentID: "1", ParentChannel: "C1", CreatedBy: "U0ALICE01", CreatedAt: now.Add(2 * time.Second)},
+		{IncidentID: "1", Channel: "C1", Type: usecaseSlack.LinkRelated, ParentID: "4", ParentChannel: "C1", CreatedBy: "U0ALICE01", CreatedAt: now.Add(3 * time.Second)},
+	} {
+		if err := store.InsertIncidentLink(ctx, link); err != nil {
+			t.Fatalf("InsertIncidentLink(%s -> %s): %v", link.IncidentID, link.ParentID, err)
+		}
+	}
+
+	parents, err := store.GetIncidentLinks(ctx, "2", "C1")
+	if err != nil || len(parents) != 1 {
+		t.Fatalf("GetIncidentLinks(2, C1) = %+v, %v; want one link", parents, err)
+	}
+	if got := parents[0]; got.Type != usecaseSlack.LinkCausedBy || got.CreatedBy != "U0BOB0001" || !got.CreatedAt.Equal(now.Add(time.Second)) {
+		t.Errorf("relinked = %+v; want the second link", got)
+	}
+
+	children, err := store.GetChildLinks(ctx, "1", "C1")
+	if err != nil || len(children) != 2 || children[0].IncidentID != "2" || children[1].IncidentID != "3" || children[1].Channel != "C2" {
+		t.Errorf("GetChildLinks(1, C1) = %+v, %v; want 2 and 3", children, err)
+	}
+	if children, err = store.GetChildLinks(ctx, "1", "C2"); err != nil || len(children) != 0 {
+		t.Errorf("GetChildLinks(1, C2) = %+v, %v; want none", children, err)
+	}
+
+	if err := store.DeleteIncidentLinks(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteIncidentLinks: %v", err)
+	}
+	for _, key := range [][2]string{{"1", "C1"}, {"2", "C1"}, {"3", "C2"}} {
+		if links, err := store.GetIncidentLinks(ctx, key[0], key[1]); err != nil || len(links) != 0 {
+			t.Errorf("GetIncidentLinks(%s, %s) after delete = %+v, %v", key[0], key[1], links, err)
+		}
+	}
+}
+
+// runTicketConformance checks that tickets are per incident and channel and
+// that storing a ticket again replaces it.
+func runTicketConformance(t *testing.T, store ticketStore) {
+	ctx := context.Background()
+
+	opened := usecaseSlack.Ticket{Key: "OPS-1", URL: "https://jira/browse/OPS-1", Status: "open"}
+	if err := store.InsertTicket(ctx, "1", "C1", opened); err != nil {
+		t.Fatalf("InsertTicket: %v", err)
+	}
+	if got, err := store.GetTicket(ctx, "1", "C1"); err != nil || got != opened {
+		t.Errorf("GetTicket(1, C1) = %+v, %v; want %+v", got, err, opened)
+	}
+	if _, err := store.GetTicket(ctx, "1", "C2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTicket(1, C2) = %v; want ErrNotFound", err)
+	}
+
+	resolved := opened
+	resolved.Status, resolved.Resolved = "Done", true
+	if err := store.InsertTicket(ctx, "1", "C1", resolved); err != nil {
+		t.Fatalf("InsertTicket(resolved): %v", err)
+	}
+	if got, err := store.GetTicket(ctx, "1", "C1"); err != nil || got != resolved {
+		t.Errorf("GetTicket(1, C1) after update = %+v, %v; want %+v", got, err, resolved)
+	}
+
+	if err := store.DeleteTicket(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteTicket: %v", err)
+	}
+	if _, err := store.GetTicket(ctx, "1", "C1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTicket(1, C1) after delete = %v; want ErrNotFound", err)
+	}
+}
+
+// runAssignmentConformance checks that the assignment history is per incident
+// and channel, kept in insertion order and removed on delete.
+func runAssignmentConformance(t *testing.T, store assignmentStore) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	history := []usecaseSlack.Assignment{
+		{IncidentID: "1", Channel: "C1", Assignee: "U0ALICE01", AssignedBy: "U0ALICE01", AssignedAt: now},
+		{IncidentID: "1", Channel: "C1", Assignee: "U0BOB0001", Previous: "U0ALICE01", AssignedBy: "U0ALICE01", AssignedAt: now},
+	}
+	for _, assignment := range append(history, usecaseSlack.Assignment{IncidentID: "1", Channel: "C2", Assignee: "U0CAROL01", AssignedAt: now}) {
+		if err := store.InsertAssignment(ctx, assignment); err != nil {
+			t.Fatalf("InsertAssignment(%s): %v", assignment.Assignee, err)
+		}
+	}
+
+	got, err := store.GetAssignments(ctx, "1", "C1")
+	if err != nil || len(got) != len(history) {
+		t.Fatalf("GetAssignments(1, C1) = %+v, %v; want %d assignments", got, err, len(history))
+	}
+	for i := range history {
+		if got[i].Assignee != history[i].Assignee || got[i].Previous != history[i].Previous || got[i].AssignedBy != history[i].AssignedBy || !got[i].AssignedAt.Equal(now) {
+			t.Errorf("assignment %d = %+v; want %+v", i, got[i], history[i])
+		}
+	}
+
+	if err := store.DeleteAssignments(ctx, "1", "C1"); err != nil {
+		t.Fatalf("DeleteAssignments: %v", err)
+	}
+	if got, err := store.GetAssignments(ctx, "1", "C1"); err != nil || len(got) != 0 {
+		t.Errorf("GetAssignments(1, C1) after delete = %+v, %v; want none", got, err)
+	}
+	if got, err := store.GetAssignments(ctx, "1", "C2"); err != nil || len(got) != 1 {
+		t.Errorf("GetAssignments(1, C2) = %+v, %v; want the other channel kept", got, err)
+	}
+}
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS newrelic_incidents (
+    id           BIGSERIAL PRIMARY KEY,
+    incident_id  TEXT        NOT NULL,
+    channel      TEXT        NOT NULL,
+    condition_id INTEGER     NOT NULL DEFAULT 0,
+    name         TEXT        NOT NULL DEFAULT '',
+    url          TEXT        NOT NULL DEFAULT '',
+    description  TEXT        NOT NULL DEFAULT '',
+    owner        TEXT        NOT NULL DEFAULT '',
+    generated_by TEXT        NOT NULL DEFAULT '',
+    status       TEXT        NOT NULL DEFAULT '',
+    severity     TEXT        NOT NULL DEFAULT '',
+    root_cause   TEXT        NOT NULL DEFAULT 'null',
+    labels       TEXT        NOT NULL DEFAULT '',
+    start_time   TIMESTAMPTZ NOT NULL,
+    recover_time TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS newrelic_incidents_incident_channel_idx ON newrelic_incidents (incident_id, channel);
+CREATE INDEX IF NOT EXISTS newrelic_incidents_start_time_idx ON newrelic_incidents (start_time);
+CREATE INDEX IF NOT EXISTS newrelic_incidents_status_idx ON newrelic_incidents (status);
+
+CREATE TABLE IF NOT EXISTS slack_messages (
+    id          BIGSERIAL PRIMARY KEY,
+    message_ts  TEXT        NOT NULL,
+    channel     TEXT        NOT NULL DEFAULT '',
+    incident_id TEXT        NOT NULL,
+    trigger_id  TEXT        NOT NULL DEFAULT '',
+    workspace   TEXT        NOT NULL DEFAULT '',
+    user_ack    TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS slack_messages_message_ts_idx ON slack_messages (message_ts);
+CREATE INDEX IF NOT EXISTS slack_messages_incident_channel_idx ON slack_messages (incident_id, channel);
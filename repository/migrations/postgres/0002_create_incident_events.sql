@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS incident_events (
+    id             BIGSERIAL PRIMARY KEY,
+    incident_id    TEXT        NOT NULL,
+    channel        TEXT        NOT NULL,
+    type           TEXT        NOT NULL,
+    state          TEXT        NOT NULL DEFAULT '',
+    previous_state TEXT        NOT NULL DEFAULT '',
+    actor          TEXT        NOT NULL DEFAULT '',
+    detail         TEXT        NOT NULL DEFAULT '',
+    payload        TEXT,
+    stale          BOOLEAN     NOT NULL DEFAULT FALSE,
+    occurred_at    TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS incident_events_incident_channel_idx ON incident_events (incident_id, channel);
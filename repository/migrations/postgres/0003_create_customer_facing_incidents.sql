@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS customer_facing_incidents (
+    incident_id TEXT        NOT NULL,
+    channel     TEXT        NOT NULL,
+    flagged_by  TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (incident_id, channel)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS action_items (
+    id          BIGSERIAL PRIMARY KEY,
+    incident_id TEXT        NOT NULL,
+    channel     TEXT        NOT NULL,
+    team        TEXT        NOT NULL DEFAULT '',
+    owner       TEXT        NOT NULL DEFAULT '',
+    description TEXT        NOT NULL,
+    status      TEXT        NOT NULL,
+    due_date    TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL,
+    reminded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS action_items_incident_channel_idx ON action_items (incident_id, channel);
+CREATE INDEX IF NOT EXISTS action_items_status_idx ON action_items (status);
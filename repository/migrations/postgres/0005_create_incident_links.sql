@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS incident_links (
+    incident_id    TEXT        NOT NULL,
+    channel        TEXT        NOT NULL,
+    type           TEXT        NOT NULL,
+    parent_id      TEXT        NOT NULL,
+    parent_channel TEXT        NOT NULL,
+    created_by     TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (incident_id, channel, parent_id, parent_channel)
+);
+
+CREATE INDEX IF NOT EXISTS incident_links_parent_idx ON incident_links (parent_id, parent_channel);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS incident_tickets (
+    incident_id TEXT    NOT NULL,
+    channel     TEXT    NOT NULL,
+    ticket_key  TEXT    NOT NULL,
+    url         TEXT    NOT NULL DEFAULT '',
+    status      TEXT    NOT NULL DEFAULT '',
+    resolved    BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (incident_id, channel)
+);
@@ -0,0 +1,3 @@
+-- Slack message timestamps are only unique within a channel.
+DROP INDEX IF EXISTS slack_messages_message_ts_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS slack_messages_channel_message_ts_idx ON slack_messages (channel, message_ts);
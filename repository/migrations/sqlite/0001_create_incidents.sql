@@ -0,0 +1,36 @@
+-- Times are stored as Unix milliseconds.
+CREATE TABLE IF NOT EXISTS newrelic_incidents (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    incident_id  TEXT    NOT NULL,
+    channel      TEXT    NOT NULL,
+    condition_id INTEGER NOT NULL DEFAULT 0,
+    name         TEXT    NOT NULL DEFAULT '',
+    url          TEXT    NOT NULL DEFAULT '',
+    description  TEXT    NOT NULL DEFAULT '',
+    owner        TEXT    NOT NULL DEFAULT '',
+    generated_by TEXT    NOT NULL DEFAULT '',
+    status       TEXT    NOT NULL DEFAULT '',
+    severity     TEXT    NOT NULL DEFAULT '',
+    root_cause   TEXT    NOT NULL DEFAULT 'null',
+    labels       TEXT    NOT NULL DEFAULT '',
+    start_time   INTEGER NOT NULL,
+    recover_time INTEGER
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS newrelic_incidents_incident_channel_idx ON newrelic_incidents (incident_id, channel);
+CREATE INDEX IF NOT EXISTS newrelic_incidents_start_time_idx ON newrelic_incidents (start_time);
+CREATE INDEX IF NOT EXISTS newrelic_incidents_status_idx ON newrelic_incidents (status);
+
+CREATE TABLE IF NOT EXISTS slack_messages (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_ts  TEXT    NOT NULL,
+    channel     TEXT    NOT NULL DEFAULT '',
+    incident_id TEXT    NOT NULL,
+    trigger_id  TEXT    NOT NULL DEFAULT '',
+    workspace   TEXT    NOT NULL DEFAULT '',
+    user_ack    TEXT    NOT NULL DEFAULT '',
+    created_at  INTEGER NOT NULL DEFAULT (CAST(strftime('%s', 'now') AS INTEGER) * 1000)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS slack_messages_message_ts_idx ON slack_messages (message_ts);
+CREATE INDEX IF NOT EXISTS slack_messages_incident_channel_idx ON slack_messages (incident_id, channel);
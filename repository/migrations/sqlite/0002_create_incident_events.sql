@@ -0,0 +1,17 @@
+-- Times are stored as Unix milliseconds.
+CREATE TABLE IF NOT EXISTS incident_events (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    incident_id    TEXT    NOT NULL,
+    channel        TEXT    NOT NULL,
+    type           TEXT    NOT NULL,
+    state          TEXT    NOT NULL DEFAULT '',
+    previous_state TEXT    NOT NULL DEFAULT '',
+    actor          TEXT    NOT NULL DEFAULT '',
+    detail         TEXT    NOT NULL DEFAULT '',
+    payload        TEXT,
+    stale          INTEGER NOT NULL DEFAULT 0,
+    occurred_at    INTEGER,
+    created_at     INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS incident_events_incident_channel_idx ON incident_events (incident_id, channel);
@@ -0,0 +1,8 @@
+-- Times are stored as Unix milliseconds.
+CREATE TABLE IF NOT EXISTS customer_facing_incidents (
+    incident_id TEXT    NOT NULL,
+    channel     TEXT    NOT NULL,
+    flagged_by  TEXT    NOT NULL DEFAULT '',
+    created_at  INTEGER NOT NULL DEFAULT (CAST(strftime('%s', 'now') AS INTEGER) * 1000),
+    PRIMARY KEY (incident_id, channel)
+);
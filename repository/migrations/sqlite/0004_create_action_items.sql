@@ -0,0 +1,16 @@
+-- Times are stored as Unix milliseconds.
+CREATE TABLE IF NOT EXISTS action_items (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    incident_id TEXT    NOT NULL,
+    channel     TEXT    NOT NULL,
+    team        TEXT    NOT NULL DEFAULT '',
+    owner       TEXT    NOT NULL DEFAULT '',
+    description TEXT    NOT NULL,
+    status      TEXT    NOT NULL,
+    due_date    INTEGER,
+    created_at  INTEGER NOT NULL,
+    reminded_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS action_items_incident_channel_idx ON action_items (incident_id, channel);
+CREATE INDEX IF NOT EXISTS action_items_status_idx ON action_items (status);
@@ -0,0 +1 @@
+ALTER TABLE customer_facing_incidents ADD COLUMN summary TEXT NOT NULL DEFAULT '';
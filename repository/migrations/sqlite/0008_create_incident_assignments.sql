@@ -0,0 +1,12 @@
+-- Times are stored as Unix milliseconds.
+CREATE TABLE IF NOT EXISTS incident_assignments (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    incident_id TEXT    NOT NULL,
+    channel     TEXT    NOT NULL,
+    assignee    TEXT    NOT NULL,
+    previous    TEXT    NOT NULL DEFAULT '',
+    assigned_by TEXT    NOT NULL DEFAULT '',
+    assigned_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS incident_assignments_incident_channel_idx ON incident_assignments (incident_id, channel);
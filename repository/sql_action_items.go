@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+const actionItemColumns = `id, incident_id, channel, team, owner, description, status, due_date, created_at, reminded_at`
+
+// InsertActionItem stores a new action item and returns it with its ID, so
+// the repository can back usecaseSlack.WithActionItems and items survive
+// restarts.
+func (r *SQLRepository) InsertActionItem(ctx context.Context, item usecaseSlack.ActionItem) (usecaseSlack.ActionItem, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, r.rebind(`INSERT INTO action_items
+		(incident_id, channel, team, owner, description, status, due_date, created_at, reminded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		item.IncidentID, item.Channel, item.Team, item.Owner, item.Description, item.Status,
+		r.timeValue(item.DueDate), r.timeValue(item.CreatedAt), r.timeValue(item.RemindedAt),
+	).Scan(&id)
+	if err != nil {
+		return usecaseSlack.ActionItem{}, err
+	}
+
+	item.ID = strconv.FormatInt(id, 10)
+	return item, nil
+}
+
+func (r *SQLRepository) UpdateActionItem(ctx context.Context, item usecaseSlack.ActionItem) error {
+	id, err := strconv.ParseInt(item.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("action item %s: %w", item.ID, usecaseSlack.ErrNotFound)
+	}
+
+	res, err := r.db.ExecContext(ctx, r.rebind(`UPDATE action_items
+		SET team = ?, owner = ?, description = ?, status = ?, due_date = ?, reminded_at = ?
+		WHERE id = ?`),
+		item.Team, item.Owner, item.Description, item.Status, r.timeValue(item.DueDate), r.timeValue(item.RemindedAt), id,
+	)
+	if err := affected(res, err); err != nil {
+		return fmt.Errorf("action item %s: %w", item.ID, err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) GetActionItem(ctx context.Context, id string) (usecaseSlack.ActionItem, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return usecaseSlack.ActionItem{}, fmt.Errorf("action item %s: %w", id, usecaseSlack.ErrNotFound)
+	}
+
+	items, err := r.queryActionItems(ctx, `WHERE id = ?`, n)
+	if err != nil {
+		return usecaseSlack.ActionItem{}, err
+	}
+	if len(items) == 0 {
+		return usecaseSlack.ActionItem{}, fmt.Errorf("action item %s: %w", id, usecaseSlack.ErrNotFound)
+	}
+	return items[0], nil
+}
+
+func (r *SQLRepository) GetActionItemsByIncident(ctx context.Context, incidentID, channel string) ([]usecaseSlack.ActionItem, error) {
+	return r.queryActionItems(ctx, `WHERE incident_id = ? AND channel = ?`, incidentID, channel)
+}
+
+func (r *SQLRepository) GetOpenActionItems(ctx context.Context) ([]usecaseSlack.ActionItem, error) {
+	return r.queryActionItems(ctx, `WHERE status = ?`, usecaseSlack.ActionItemOpen)
+}
+
+func (r *SQLRepository) DeleteActionItems(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM action_items WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+
+	return err
+}
+
+// queryActionItems returns the action items matching where, oldest first.
+func (r *SQLRepository) queryActionItems(ctx context.Context, where string, args ...interface{}) ([]usecaseSlack.ActionItem, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT `+actionItemColumns+` FROM action_items `+where+` ORDER BY created_at, id`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []usecaseSlack.ActionItem{}
+	for rows.Next() {
+		item := usecaseSlack.ActionItem{}
+		var id int64
+		var dueDate, createdAt, remindedAt sqlTime
+		if err := rows.Scan(&id, &item.IncidentID, &item.Channel, &item.Team, &item.Owner, &item.Description, &item.Status,
+			&dueDate, &createdAt, &remindedAt); err != nil {
+			return nil, err
+		}
+
+		item.ID = strconv.FormatInt(id, 10)
+		item.DueDate, item.CreatedAt, item.RemindedAt = dueDate.Time, createdAt.Time, remindedAt.Time
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
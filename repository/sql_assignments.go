@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+// InsertAssignment appends to the assignment history of an incident, so the
+// repository can back usecaseSlack.WithAssignments.
+func (r *SQLRepository) InsertAssignment(ctx context.Context, assignment usecaseSlack.Assignment) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO incident_assignments
+		(incident_id, channel, assignee, previous, assigned_by, assigned_at)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		assignment.IncidentID, assignment.Channel, assignment.Assignee, assignment.Previous, assignment.AssignedBy, r.timeValue(assignment.AssignedAt),
+	)
+
+	return err
+}
+
+// GetAssignments returns the assignment history of an incident, oldest first.
+func (r *SQLRepository) GetAssignments(ctx context.Context, incidentID, channel string) ([]usecaseSlack.Assignment, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT incident_id, channel, assignee, previous, assigned_by, assigned_at
+		FROM incident_assignments WHERE incident_id = ? AND channel = ? ORDER BY id`), incidentID, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []usecaseSlack.Assignment{}
+	for rows.Next() {
+		assignment := usecaseSlack.Assignment{}
+		var assignedAt sqlTime
+		if err := rows.Scan(&assignment.IncidentID, &assignment.Channel, &assignment.Assignee, &assignment.Previous, &assignment.AssignedBy, &assignedAt); err != nil {
+			return nil, err
+		}
+
+		assignment.AssignedAt = assignedAt.Time
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, rows.Err()
+}
+
+func (r *SQLRepository) DeleteAssignments(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM incident_assignments WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+
+	return err
+}
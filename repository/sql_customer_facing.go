@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+// InsertCustomerFacing flags an incident for the status page, so the
+// repository can back usecaseSlack.WithCustomerFacingStore. Flagging it again
+// keeps the first actor and replaces the summary unless it is empty.
+func (r *SQLRepository) InsertCustomerFacing(ctx context.Context, incidentID, channel, actor, summary string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO customer_facing_incidents (incident_id, channel, flagged_by, summary)
+		VALUES (?, ?, ?, ?) ON CONFLICT (incident_id, channel) DO UPDATE
+		SET summary = CASE WHEN excluded.summary = '' THEN customer_facing_incidents.summary ELSE excluded.summary END`),
+		incidentID, channel, actor, summary)
+
+	return err
+}
+
+func (r *SQLRepository) IsCustomerFacing(ctx context.Context, incidentID, channel string) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT COUNT(*) FROM customer_facing_incidents
+		WHERE incident_id = ? AND channel = ?`), incidentID, channel).Scan(&n)
+
+	return n > 0, err
+}
+
+func (r *SQLRepository) GetCustomerFacingSummary(ctx context.Context, incidentID, channel string) (string, error) {
+	var summary string
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT summary FROM customer_facing_incidents
+		WHERE incident_id = ? AND channel = ?`), incidentID, channel).Scan(&summary)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", usecaseSlack.ErrNotFound
+	}
+
+	return summary, err
+}
+
+func (r *SQLRepository) DeleteCustomerFacing(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM customer_facing_incidents
+		WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+
+	return err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+// InsertIncidentEvent appends to the incident event log, so the repository
+// can back usecaseSlack.WithEventLog and timelines survive restarts.
+func (r *SQLRepository) InsertIncidentEvent(ctx context.Context, event usecaseSlack.IncidentEvent) error {
+	var payload interface{}
+	if len(event.Payload) > 0 {
+		payload = string(event.Payload)
+	}
+
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO incident_events
+		(incident_id, channel, type, state, previous_state, actor, detail, payload, stale, occurred_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		event.IncidentID, event.Channel, event.Type, event.State, event.PreviousState, event.Actor, event.Detail,
+		payload, event.Stale, r.timeValue(event.OccurredAt), r.timeValue(event.CreatedAt),
+	)
+
+	return err
+}
+
+// GetIncidentEvents returns the events of an incident in a channel in insertion order.
+func (r *SQLRepository) GetIncidentEvents(ctx context.Context, incidentID, channel string) ([]usecaseSlack.IncidentEvent, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT incident_id, channel, type, state, previous_state, actor, detail,
+		payload, stale, occurred_at, created_at
+		FROM incident_events WHERE incident_id = ? AND channel = ? ORDER BY id`), incidentID, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []usecaseSlack.IncidentEvent{}
+	for rows.Next() {
+		event := usecaseSlack.IncidentEvent{}
+		var payload sql.NullString
+		var occurredAt, createdAt sqlTime
+		if err := rows.Scan(
+			&event.IncidentID, &event.Channel, &event.Type, &event.State, &event.PreviousState, &event.Actor, &event.Detail,
+			&payload, &event.Stale, &occurredAt, &createdAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if payload.Valid {
+			event.Payload = []byte(payload.String)
+		}
+		event.OccurredAt = occurredAt.Time
+		event.CreatedAt = createdAt.Time
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *SQLRepository) DeleteIncidentEvents(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM incident_events WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+
+	return err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+// InsertIncidentLink stores a link, so the repository can back
+// usecaseSlack.WithLinks. Linking the same incidents again replaces the link.
+func (r *SQLRepository) InsertIncidentLink(ctx context.Context, link usecaseSlack.IncidentLink) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO incident_links
+		(incident_id, channel, type, parent_id, parent_channel, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (incident_id, channel, parent_id, parent_channel)
+		DO UPDATE SET type = excluded.type, created_by = excluded.created_by, created_at = excluded.created_at`),
+		link.IncidentID, link.Channel, link.Type, link.ParentID, link.ParentChannel, link.CreatedBy, r.timeValue(link.CreatedAt),
+	)
+
+	return err
+}
+
+// GetIncidentLinks returns the links of an incident to its parents.
+func (r *SQLRepository) GetIncidentLinks(ctx context.Context, incidentID, channel string) ([]usecaseSlack.IncidentLink, error) {
+	return r.queryLinks(ctx, `WHERE incident_id = ? AND channel = ?`, incidentID, channel)
+}
+
+// GetChildLinks returns the links of the children of an incident.
+func (r *SQLRepository) GetChildLinks(ctx context.Context, parentID, parentChannel string) ([]usecaseSlack.IncidentLink, error) {
+	return r.queryLinks(ctx, `WHERE parent_id = ? AND parent_channel = ?`, parentID, parentChannel)
+}
+
+func (r *SQLRepository) DeleteIncidentLinks(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM incident_links
+		WHERE (incident_id = ? AND channel = ?) OR (parent_id = ? AND parent_channel = ?)`), incidentID, channel, incidentID, channel)
+
+	return err
+}
+
+// queryLinks returns the links matching where, oldest first.
+func (r *SQLRepository) queryLinks(ctx context.Context, where string, args ...interface{}) ([]usecaseSlack.IncidentLink, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT incident_id, channel, type, parent_id, parent_channel, created_by, created_at
+		FROM incident_links `+where+` ORDER BY created_at`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []usecaseSlack.IncidentLink{}
+	for rows.Next() {
+		link := usecaseSlack.IncidentLink{}
+		var createdAt sqlTime
+		if err := rows.Scan(&link.IncidentID, &link.Channel, &link.Type, &link.ParentID, &link.ParentChannel, &link.CreatedBy, &createdAt); err != nil {
+			return nil, err
+		}
+
+		link.CreatedAt = createdAt.Time
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
@@ -0,0 +1,532 @@
+// Package repository stores diary incidents and Slack messages in SQL
+// databases for the slack usecase.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// SlackAPI is the Slack API half of the usecase repository, served by the
+// Slack client next to a SQL store.
+type SlackAPI interface {
+	SendMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error)
+	UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error)
+	ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error)
+	SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error)
+	ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error)
+}
+
+// Optional Slack client capabilities the usecase checks its repository for.
+type (
+	threadRepository interface {
+		GetThreadReplies(ctx context.Context, channel, messageTimestamp string) ([]slack.Message, error)
+	}
+	blockRepository interface {
+		ReplyBlocksInThread(ctx context.Context, channel, messageTimestamp, fallback string, blocks ...slack.Block) (string, error)
+	}
+	viewRepository interface {
+		OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error
+	}
+	userRepository interface {
+		GetUserInfo(ctx context.Context, userID string) (*slack.User, error)
+	}
+	ackFormRepository interface {
+		SubmitButtonActionWithForm(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool, formBlocks ...slack.Block) (string, error)
+	}
+)
+
+// sqlDialect holds what differs between the supported databases.
+type sqlDialect struct {
+	name string
+	// numbered placeholders ($1, $2, ...) instead of ?
+	numbered bool
+	// times stored as Unix milliseconds instead of timestamps
+	unixMillis bool
+}
+
+var (
+	postgresDialect = sqlDialect{name: "postgres", numbered: true}
+	sqliteDialect   = sqlDialect{name: "sqlite", unixMillis: true}
+)
+
+// SQLRepository is a usecase repository storing incidents and messages in
+// PostgreSQL or SQLite, with Slack calls served by the embedded SlackAPI.
+// The caller opens db with a driver of its choice and runs Migrate once.
+type SQLRepository struct {
+	SlackAPI
+
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+var _ usecaseSlack.Repository = (*SQLRepository)(nil)
+
+func NewPostgresRepository(db *sql.DB, api SlackAPI) *SQLRepository {
+	return &SQLRepository{SlackAPI: api, db: db, dialect: postgresDialect}
+}
+
+func NewSQLiteRepository(db *sql.DB, api SlackAPI) *SQLRepository {
+	return &SQLRepository{SlackAPI: api, db: db, dialect: sqliteDialect}
+}
+
+// Migrate applies the embedded schema migrations of the dialect that have
+// not run yet, each in its own transaction, in file name order.
+func (r *SQLRepository) Migrate(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	dir := path.Join("migrations", r.dialect.name)
+	files, err := fs.Glob(migrationFiles, dir+"/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(path.Base(file), ".sql")
+
+		var applied int
+		if err := r.db.QueryRowContext(ctx, r.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		schema, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := r.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, string(schema)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version)
+			return err
+		}); err != nil {
+			return fmt.Errorf("migration %s: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+const incidentColumns = `i.incident_id, i.channel, i.condition_id, i.name, i.url, i.description, i.owner, i.generated_by,
+	i.status, i.severity, i.root_cause, i.labels, i.start_time, i.recover_time,
+	COALESCE(m.message_ts, ''), COALESCE(m.user_ack, '')`
+
+const incidentJoin = `newrelic_incidents i
+	LEFT JOIN slack_messages m ON m.incident_id = i.incident_id AND m.channel = i.channel`
+
+func (r *SQLRepository) GetNewRelicIncident(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	return r.GetNewRelicIncidentByID(ctx, incidentID, channel)
+}
+
+func (r *SQLRepository) GetNewRelicIncidentByID(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+incidentColumns+` FROM `+incidentJoin+`
+		WHERE i.incident_id = ? AND i.channel = ?`), incidentID, channel)
+
+	return r.scanIncident(row)
+}
+
+func (r *SQLRepository) GetNewRelicIncidentByMsgTimestamp(ctx context.Context, incidentID, messageTimestamp string) (entitySlack.Incident, error) {
+	row := r.db.QueryRowContext(ctx, r.rebind(`SELECT `+incidentColumns+` FROM `+incidentJoin+`
+		WHERE i.incident_id = ? AND m.message_ts = ?`), incidentID, messageTimestamp)
+
+	return r.scanIncident(row)
+}
+
+func (r *SQLRepository) InsertNewRelicIncident(ctx context.Context, incidentID string, conditionID int, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels string, startTime, recoverTime time.Time) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO newrelic_incidents
+		(incident_id, channel, condition_id, name, url, description, owner, generated_by, status, severity, root_cause, labels, start_time, recover_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		incidentID, channel, conditionID, name, url, description, owner, generatedBy, status, severity, rootCause, labels,
+		r.timeValue(startTime), r.timeValue(recoverTime),
+	)
+
+	return err
+}
+
+func (r *SQLRepository) UpdateNewRelicIncidentStatusByID(ctx context.Context, status, messageTimestamp, channel, incidentID string, recoverTime time.Time) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`UPDATE newrelic_incidents SET status = ?, recover_time = ?
+		WHERE incident_id = ? AND channel = ?`), status, r.timeValue(recoverTime), incidentID, channel)
+
+	return affected(res, err)
+}
+
+// UpdateNewRelicIncidentByID sets the root cause of the incident in every
+// channel it was posted to.
+func (r *SQLRepository) UpdateNewRelicIncidentByID(ctx context.Context, rootCause, incidentID string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`UPDATE newrelic_incidents SET root_cause = ? WHERE incident_id = ?`), rootCause, incidentID)
+
+	return affected(res, err)
+}
+
+// UpdateNewRelicIncidentSeverityByID stores the raised severity of an
+// escalated incident.
+func (r *SQLRepository) UpdateNewRelicIncidentSeverityByID(ctx context.Context, severity, incidentID, channel string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`UPDATE newrelic_incidents SET severity = ? WHERE incident_id = ? AND channel = ?`), severity, incidentID, channel)
+
+	return affected(res, err)
+}
+
+// InsertMessage stores a message in the channel of the latest incident with
+// incidentID, for callers that do not know the channel.
+func (r *SQLRepository) InsertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID string) error {
+	var channel string
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT channel FROM newrelic_incidents WHERE incident_id = ?
+		ORDER BY start_time DESC LIMIT 1`), incidentID).Scan(&channel)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return r.InsertChannelMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID, channel)
+}
+
+func (r *SQLRepository) InsertChannelMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO slack_messages (message_ts, channel, incident_id, trigger_id, workspace, user_ack)
+		VALUES (?, ?, ?, ?, ?, ?)`), messageTimestamp, channel, incidentID, triggerID, workspace, userACK)
+
+	return err
+}
+
+func (r *SQLRepository) UpdateMessageByTimestamp(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, channel string) error {
+	res, err := r.db.ExecContext(ctx, r.rebind(`UPDATE slack_messages SET trigger_id = ?, workspace = ?, user_ack = ?
+		WHERE message_ts = ? AND (channel = ? OR channel = '')`), triggerID, workspace, userACK, messageTimestamp, channel)
+
+	return affected(res, err)
+}
+
+func (r *SQLRepository) GetMessageByTimestamp(ctx context.Context, messageTimestamp, channel string) (entitySlack.Message, error) {
+	message := entitySlack.Message{}
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT trigger_id, workspace, user_ack, message_ts, incident_id FROM slack_messages
+		WHERE message_ts = ? AND (channel = ? OR channel = '') ORDER BY channel DESC LIMIT 1`), messageTimestamp, channel).
+		Scan(&message.TriggerID, &message.Workspace, &message.UserACK, &message.MessageTimestamp, &message.IncidentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return message, usecaseSlack.ErrNotFound
+	}
+
+	return message, err
+}
+
+// SearchIncidents filters in SQL; label filters are applied on the decoded
+// labels afterwards, so queries with labels page in memory.
+func (r *SQLRepository) SearchIncidents(ctx context.Context, query usecaseSlack.IncidentQuery) ([]entitySlack.Incident, int, error) {
+	query = query.Normalized()
+
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if len(query.Status) > 0 {
+		placeholders := []string{}
+		for _, status := range query.Status {
+			placeholders = append(placeholders, "?")
+			args = append(args, strings.ToLower(status))
+		}
+		where = append(where, "LOWER(i.status) IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	for column, value := range map[string]string{
+		"i.generated_by": query.Vendor,
+		"i.owner":        query.Owner,
+		"i.root_cause":   query.RootCause,
+	} {
+		if value != "" {
+			where = append(where, "LOWER("+column+") = LOWER(?)")
+			args = append(args, value)
+		}
+	}
+	if query.Severity != "" {
+		clause, severityArgs := severityFilter(usecaseSlack.NormalizeSeverity(query.Severity))
+		where = append(where, clause)
+		args = append(args, severityArgs...)
+	}
+	if query.Unposted {
+		where = append(where, "m.message_ts IS NULL")
+	}
+	if query.Channel != "" {
+		where = append(where, "i.channel = ?")
+		args = append(args, query.Channel)
+	}
+	if !query.From.IsZero() {
+		where = append(where, "i.start_time >= ?")
+		args = append(args, r.timeValue(query.From))
+	}
+	if !query.To.IsZero() {
+		where = append(where, "i.start_time < ?")
+		args = append(args, r.timeValue(query.To))
+	}
+	filter := strings.Join(where, " AND ")
+
+	if len(query.Labels) > 0 {
+		incidents, err := r.queryIncidents(ctx, `SELECT `+incidentColumns+` FROM `+incidentJoin+` WHERE `+filter+` ORDER BY i.start_time DESC, i.id DESC`, args...)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		matches := []entitySlack.Incident{}
+		for _, incident := range incidents {
+			if query.Match(incident) {
+				matches = append(matches, incident)
+			}
+		}
+		total := len(matches)
+		if query.Offset >= total {
+			return []entitySlack.Incident{}, total, nil
+		}
+		end := query.Offset + query.Limit
+		if end > total {
+			end = total
+		}
+		return matches[query.Offset:end], total, nil
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, r.rebind(`SELECT COUNT(*) FROM `+incidentJoin+` WHERE `+filter), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	incidents, err := r.queryIncidents(ctx, `SELECT `+incidentColumns+` FROM `+incidentJoin+` WHERE `+filter+`
+		ORDER BY i.start_time DESC, i.id DESC LIMIT `+strconv.Itoa(query.Limit)+` OFFSET `+strconv.Itoa(query.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return incidents, total, nil
+}
+
+// severityFilter matches the incidents whose stored severity normalizes to
+// severity, like IncidentQuery.Match. Values normalize to DefaultSeverity when
+// they are not a known severity at all.
+func severityFilter(severity usecaseSlack.Severity) (string, []interface{}) {
+	args := []interface{}{}
+	in := func(values []string) string {
+		placeholders := []string{}
+		for _, value := range values {
+			placeholders = append(placeholders, "?")
+			args = append(args, value)
+		}
+		return "LOWER(TRIM(i.severity)) IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	clause := in(usecaseSlack.SeverityAliases(severity))
+	if severity == usecaseSlack.DefaultSeverity {
+		clause = "(" + clause + " OR i.severity IS NULL OR NOT " + in(usecaseSlack.KnownSeverities()) + ")"
+	}
+
+	return clause, args
+}
+
+func (r *SQLRepository) DeleteNewRelicIncident(ctx context.Context, incidentID, channel string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, r.rebind(`DELETE FROM newrelic_incidents WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+		if err := affected(res, err); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.rebind(`DELETE FROM slack_messages WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+		return err
+	})
+}
+
+// GetThreadReplies forwards to the Slack client when it can read threads.
+func (r *SQLRepository) GetThreadReplies(ctx context.Context, channel, messageTimestamp string) ([]slack.Message, error) {
+	threads, ok := r.SlackAPI.(threadRepository)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot read thread replies", r.SlackAPI)
+	}
+
+	return threads.GetThreadReplies(ctx, channel, messageTimestamp)
+}
+
+// ReplyBlocksInThread forwards to the Slack client when it can post blocks.
+func (r *SQLRepository) ReplyBlocksInThread(ctx context.Context, channel, messageTimestamp, fallback string, blocks ...slack.Block) (string, error) {
+	blockReplies, ok := r.SlackAPI.(blockRepository)
+	if !ok {
+		return "", fmt.Errorf("%T cannot post blocks", r.SlackAPI)
+	}
+
+	return blockReplies.ReplyBlocksInThread(ctx, channel, messageTimestamp, fallback, blocks...)
+}
+
+// SubmitButtonActionWithForm forwards to the Slack client when it can render
+// form blocks, and opens the form without them otherwise.
+func (r *SQLRepository) SubmitButtonActionWithForm(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool, formBlocks ...slack.Block) (string, error) {
+	forms, ok := r.SlackAPI.(ackFormRepository)
+	if !ok {
+		return r.SlackAPI.SubmitButtonAction(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal)
+	}
+
+	return forms.SubmitButtonActionWithForm(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal, formBlocks...)
+}
+
+// OpenView forwards to the Slack client when it can open modals.
+func (r *SQLRepository) OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error {
+	views, ok := r.SlackAPI.(viewRepository)
+	if !ok {
+		return fmt.Errorf("%T cannot open modals", r.SlackAPI)
+	}
+
+	return views.OpenView(ctx, triggerID, view)
+}
+
+// GetUserInfo forwards to the Slack client when it can call users.info.
+func (r *SQLRepository) GetUserInfo(ctx context.Context, userID string) (*slack.User, error) {
+	users, ok := r.SlackAPI.(userRepository)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot look up users", r.SlackAPI)
+	}
+
+	return users.GetUserInfo(ctx, userID)
+}
+
+func (r *SQLRepository) queryIncidents(ctx context.Context, query string, args ...interface{}) ([]entitySlack.Incident, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := []entitySlack.Incident{}
+	for rows.Next() {
+		incident, err := r.scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *SQLRepository) scanIncident(row rowScanner) (entitySlack.Incident, error) {
+	incident := entitySlack.Incident{}
+	var startTime, recoverTime sqlTime
+	err := row.Scan(
+		&incident.IncidentID, &incident.Channel, &incident.ConditionID, &incident.Name, &incident.URL, &incident.Description,
+		&incident.Owner, &incident.GeneratedBy, &incident.Status, &incident.Severity, &incident.RootCause, &incident.Labels,
+		&startTime, &recoverTime, &incident.MessageTimestamp, &incident.UserACK,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return incident, usecaseSlack.ErrNotFound
+	}
+	if err != nil {
+		return incident, err
+	}
+
+	incident.StartTime = startTime.Time
+	incident.RecoverTime = recoverTime.Time
+	return incident, nil
+}
+
+// timeValue encodes t for the dialect; the zero time is stored as NULL.
+func (r *SQLRepository) timeValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	if r.dialect.unixMillis {
+		return t.UnixMilli()
+	}
+
+	return t
+}
+
+// rebind rewrites ? placeholders for dialects with numbered placeholders.
+// Queries must not contain literal question marks.
+func (r *SQLRepository) rebind(query string) string {
+	if !r.dialect.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (r *SQLRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return usecaseSlack.ErrNotFound
+	}
+	return nil
+}
+
+// sqlTime scans timestamps, Unix milliseconds and NULL into a time.Time.
+type sqlTime struct {
+	Time time.Time
+}
+
+func (t *sqlTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v.Local()
+	case int64:
+		t.Time = time.UnixMilli(v).Local()
+	case []byte:
+		return t.Scan(string(v))
+	case string:
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			t.Time = time.UnixMilli(ms).Local()
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fmt.Errorf("scan time %q: %w", v, err)
+		}
+		t.Time = parsed.Local()
+	default:
+		return fmt.Errorf("scan time: unsupported type %T", src)
+	}
+
+	return nil
+}
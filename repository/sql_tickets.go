@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	usecaseSlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+)
+
+// InsertTicket stores the ticket of an incident, so the repository can back
+// usecaseSlack.WithTicketStore. Storing it again replaces it.
+func (r *SQLRepository) InsertTicket(ctx context.Context, incidentID, channel string, ticket usecaseSlack.Ticket) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`INSERT INTO incident_tickets (incident_id, channel, ticket_key, url, status, resolved)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (incident_id, channel)
+		DO UPDATE SET ticket_key = excluded.ticket_key, url = excluded.url, status = excluded.status, resolved = excluded.resolved`),
+		incidentID, channel, ticket.Key, ticket.URL, ticket.Status, ticket.Resolved)
+
+	return err
+}
+
+func (r *SQLRepository) GetTicket(ctx context.Context, incidentID, channel string) (usecaseSlack.Ticket, error) {
+	ticket := usecaseSlack.Ticket{}
+	err := r.db.QueryRowContext(ctx, r.rebind(`SELECT ticket_key, url, status, resolved FROM incident_tickets
+		WHERE incident_id = ? AND channel = ?`), incidentID, channel).
+		Scan(&ticket.Key, &ticket.URL, &ticket.Status, &ticket.Resolved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ticket, usecaseSlack.ErrNotFound
+	}
+
+	return ticket, err
+}
+
+func (r *SQLRepository) DeleteTicket(ctx context.Context, incidentID, channel string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM incident_tickets WHERE incident_id = ? AND channel = ?`), incidentID, channel)
+
+	return err
+}
@@ -0,0 +1,386 @@
+package slack
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// ErrArchiveUnsupported is returned when the repository cannot delete incidents.
+var ErrArchiveUnsupported = errors.New("incident archival is not supported by the repository")
+
+// incidentDeleter is implemented by repositories that can remove an incident
+// and its Slack message rows from the primary store.
+type incidentDeleter interface {
+	DeleteNewRelicIncident(ctx context.Context, incidentID, channel string) error
+}
+
+// RetentionPolicy archives closed incidents that started more than Days days ago into Dir.
+type RetentionPolicy struct {
+	Days int
+	Dir  string
+}
+
+// ArchivedIncident is one line of an archive file. It holds everything the
+// incident owns, so nothing is left behind in the other stores.
+type ArchivedIncident struct {
+	Incident       ExportRow       `json:"incident"`
+	Events         []IncidentEvent `json:"events,omitempty"`
+	ActionItems    []ActionItem    `json:"action_items,omitempty"`
+	Links          []IncidentLink  `json:"links,omitempty"`
+	Ticket         *Ticket         `json:"ticket,omitempty"`
+	Assignments    []Assignment    `json:"assignments,omitempty"`
+	CustomerFacing bool            `json:"customer_facing,omitempty"`
+	// CustomerFacingSummary is what the status page published it as.
+	CustomerFacingSummary string `json:"customer_facing_summary,omitempty"`
+}
+
+const archiveFilePattern = "incidents-*.jsonl.gz"
+
+// ArchiveIncidents moves closed incidents older than the policy into a new
+// gzip-compressed JSONL file, then deletes them from the primary store along
+// with their events, action items, links, ticket, assignments and
+// customer-facing flag. Incidents are only deleted once the archive file is
+// completely written. Incidents with open action items are kept until the
+// items are done or dropped, so their reminders keep running.
+func (u *UseCase) ArchiveIncidents(ctx context.Context, policy RetentionPolicy, now time.Time) (int, error) {
+	deleter, ok := u.baseRepo.(incidentDeleter)
+	if !ok {
+		return 0, ErrArchiveUnsupported
+	}
+	if policy.Days <= 0 {
+		return 0, fmt.Errorf("retention days must be positive")
+	}
+	if err := os.MkdirAll(policy.Dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	archived := []entitySlack.Incident{}
+	query := IncidentQuery{Status: []string{"closed"}, To: now.AddDate(0, 0, -policy.Days), Limit: maxSearchLimit}
+	for {
+		incidents, total, err := u.SearchIncidents(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+		for _, incident := range incidents {
+			open, err := u.hasOpenActionItems(ctx, incident)
+			if err != nil {
+				return 0, err
+			}
+			if !open {
+				archived = append(archived, incident)
+			}
+		}
+
+		query.Offset += len(incidents)
+		if len(incidents) == 0 || query.Offset >= total {
+			break
+		}
+	}
+	if len(archived) == 0 {
+		return 0, nil
+	}
+
+	path := filepath.Join(policy.Dir, fmt.Sprintf("incidents-%s.jsonl.gz", now.UTC().Format("20060102-150405")))
+	if err := u.writeArchive(ctx, path, archived); err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	count := 0
+	for _, incident := range archived {
+		if err := deleter.DeleteNewRelicIncident(ctx, incident.IncidentID, incident.Channel); err != nil {
+			log.Errorf("Error delete archived incident %s from database: %s", incident.IncidentID, err)
+			continue
+		}
+		u.deleteIncidentData(ctx, incident.IncidentID, incident.Channel)
+		count++
+	}
+
+	return count, nil
+}
+
+func (u *UseCase) hasOpenActionItems(ctx context.Context, incident entitySlack.Incident) (bool, error) {
+	items, err := u.actionItems.GetActionItemsByIncident(ctx, incident.IncidentID, incident.Channel)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range items {
+		if item.Status == ActionItemOpen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deleteIncidentData removes what an archived incident owns in the other
+// stores. Failures are logged: the archive already holds the data.
+func (u *UseCase) deleteIncidentData(ctx context.Context, incidentID, channel string) {
+	for _, store := range []struct {
+		name   string
+		delete func(context.Context, string, string) error
+	}{
+		{"incident events", u.events.DeleteIncidentEvents},
+		{"action items", u.actionItems.DeleteActionItems},
+		{"incident links", u.links.DeleteIncidentLinks},
+		{"ticket", u.ticketStore.DeleteTicket},
+		{"assignments", u.assignments.DeleteAssignments},
+		{"customer-facing flag", u.customerFacing.DeleteCustomerFacing},
+	} {
+		if err := store.delete(ctx, incidentID, channel); err != nil {
+			log.Errorf("Error delete %s of archived incident %s from database: %s", store.name, incidentID, err)
+		}
+	}
+}
+
+// archivedIncident collects what the incident owns in every store.
+func (u *UseCase) archivedIncident(ctx context.Context, incident entitySlack.Incident) (ArchivedIncident, error) {
+	archived := ArchivedIncident{Incident: u.exportRow(ctx, incident)}
+
+	var err error
+	if archived.Events, err = u.events.GetIncidentEvents(ctx, incident.IncidentID, incident.Channel); err != nil {
+		return archived, err
+	}
+
+	if archived.ActionItems, err = u.actionItems.GetActionItemsByIncident(ctx, incident.IncidentID, incident.Channel); err != nil {
+		return archived, err
+	}
+
+	parents, children, err := u.GetIncidentLinks(ctx, incident.IncidentID, incident.Channel)
+	if err != nil {
+		return archived, err
+	}
+	archived.Links = append(parents, children...)
+
+	ticket, err := u.ticketStore.GetTicket(ctx, incident.IncidentID, incident.Channel)
+	switch {
+	case err == nil:
+		archived.Ticket = &ticket
+	case !errors.Is(err, ErrNotFound):
+		return archived, err
+	}
+
+	if archived.Assignments, err = u.assignments.GetAssignments(ctx, incident.IncidentID, incident.Channel); err != nil {
+		return archived, err
+	}
+
+	if archived.CustomerFacing, err = u.customerFacing.IsCustomerFacing(ctx, incident.IncidentID, incident.Channel); err != nil || !archived.CustomerFacing {
+		return archived, err
+	}
+
+	archived.CustomerFacingSummary, err = u.customerFacing.GetCustomerFacingSummary(ctx, incident.IncidentID, incident.Channel)
+	return archived, err
+}
+
+func (u *UseCase) writeArchive(ctx context.Context, path string, incidents []entitySlack.Incident) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, incident := range incidents {
+		line, err := u.archivedIncident(ctx, incident)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// RestoreIncident finds an archived incident by ID in dir, newest archive
+// first, and inserts it back into the primary store in every channel it was
+// archived from.
+func (u *UseCase) RestoreIncident(ctx context.Context, dir, incidentID string) ([]entitySlack.Incident, error) {
+	files, err := filepath.Glob(filepath.Join(dir, archiveFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	for _, file := range files {
+		found, err := findArchived(file, incidentID)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		restored := []entitySlack.Incident{}
+		for _, archived := range found {
+			incident, err := u.restore(ctx, archived)
+			if err != nil {
+				return restored, err
+			}
+			restored = append(restored, incident)
+		}
+		return restored, nil
+	}
+
+	return nil, fmt.Errorf("incident %s: %w", incidentID, ErrNotFound)
+}
+
+func (u *UseCase) restore(ctx context.Context, archived ArchivedIncident) (entitySlack.Incident, error) {
+	row := archived.Incident
+
+	rootCause := row.RootCause
+	if rootCause == "" {
+		rootCause = "null"
+	}
+	recoverTime := time.Time{}
+	if row.RecoverTime > 0 {
+		recoverTime = time.UnixMilli(row.RecoverTime).Local()
+	}
+
+	if err := u.slackRepo.InsertNewRelicIncident(ctx, row.IncidentID, int(row.ConditionID), row.Name, row.URL, row.Description, row.Owner, row.Vendor, row.Status, row.Severity, rootCause, row.Channel, row.Labels, time.UnixMilli(row.StartTime).Local(), recoverTime); err != nil {
+		return entitySlack.Incident{}, err
+	}
+	if row.MessageTs != "" {
+		if err := u.insertMessage(ctx, "", "", row.UserACK, row.MessageTs, row.IncidentID, row.Channel); err != nil {
+			return entitySlack.Incident{}, err
+		}
+	}
+
+	// Incidents archived before events were deleted with them still have their history.
+	if !u.hasEvent(ctx, row.IncidentID, row.Channel, EventReceived) {
+		for _, event := range archived.Events {
+			if err := u.events.InsertIncidentEvent(ctx, event); err != nil {
+				log.Errorf("Error store incident event to database: %s", err)
+			}
+		}
+	}
+	u.restoreIncidentData(ctx, archived)
+
+	return u.slackRepo.GetNewRelicIncidentByID(ctx, row.IncidentID, row.Channel)
+}
+
+// restoreIncidentData puts back what the incident owned in the other stores.
+// Action items get new IDs. Failures are logged like for events.
+func (u *UseCase) restoreIncidentData(ctx context.Context, archived ArchivedIncident) {
+	row := archived.Incident
+
+	for _, item := range archived.ActionItems {
+		if _, err := u.actionItems.InsertActionItem(ctx, item); err != nil {
+			log.Errorf("Error store action item to database: %s", err)
+		}
+	}
+
+	for _, link := range archived.Links {
+		if err := u.links.InsertIncidentLink(ctx, link); err != nil {
+			log.Errorf("Error store incident link to database: %s", err)
+		}
+	}
+
+	if archived.Ticket != nil {
+		if err := u.ticketStore.InsertTicket(ctx, row.IncidentID, row.Channel, *archived.Ticket); err != nil {
+			log.Errorf("Error store ticket to database: %s", err)
+		}
+	}
+
+	for _, assignment := range archived.Assignments {
+		if err := u.assignments.InsertAssignment(ctx, assignment); err != nil {
+			log.Errorf("Error store assignment to database: %s", err)
+		}
+	}
+
+	if archived.CustomerFacing {
+		if err := u.customerFacing.InsertCustomerFacing(ctx, row.IncidentID, row.Channel, "", archived.CustomerFacingSummary); err != nil {
+			log.Errorf("Error store customer-facing flag to database: %s", err)
+		}
+	}
+}
+
+func findArchived(path, incidentID string) ([]ArchivedIncident, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+
+	found := []ArchivedIncident{}
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var archived ArchivedIncident
+		if err := json.Unmarshal(scanner.Bytes(), &archived); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if archived.Incident.IncidentID == incidentID {
+			found = append(found, archived)
+		}
+	}
+
+	return found, scanner.Err()
+}
+
+// RunRetention applies the policy every interval until ctx is done.
+func (u *UseCase) RunRetention(ctx context.Context, policy RetentionPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := u.ArchiveIncidents(ctx, policy, now)
+			if err != nil {
+				log.Errorf("Failed archive incidents because: %s", err)
+				continue
+			}
+			if count > 0 {
+				log.Infof("Archived %d incidents older than %d days to %s", count, policy.Days, policy.Dir)
+			}
+		}
+	}
+}
+
+func (m *MemoryRepository) DeleteNewRelicIncident(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.fail("DeleteNewRelicIncident"); err != nil {
+		return err
+	}
+
+	key := incidentKey{incidentID, channel}
+	if _, ok := m.incidents[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.incidents, key)
+
+	for key, message := range m.messages {
+		if message.IncidentID == incidentID && message.channel == channel {
+			delete(m.messages, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package slack
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestArchiveIncidentsCascades(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+	now := time.Now()
+	old := now.AddDate(0, 0, -100)
+
+	for _, incident := range []struct{ id, status string }{{"42", "closed"}, {"43", "closed"}, {"44", "open"}} {
+		if err := repo.InsertNewRelicIncident(ctx, incident.id, 7, "CPU high", "", "", "", "newrelic", incident.status, "critical", "null", "C1", "", old, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.InsertChannelMessage(ctx, "", "", "U0ALICE01", "1700000000.000100", "42", "C1")
+
+	u.recordEvent(ctx, IncidentEvent{IncidentID: "42", Channel: "C1", Type: EventReceived, State: "closed"})
+	u.actionItems.InsertActionItem(ctx, ActionItem{IncidentID: "42", Channel: "C1", Description: "Add disk alerts", Status: ActionItemDone})
+	u.actionItems.InsertActionItem(ctx, ActionItem{IncidentID: "43", Channel: "C1", Description: "Rotate keys", Status: ActionItemOpen})
+	u.links.InsertIncidentLink(ctx, IncidentLink{IncidentID: "44", Channel: "C1", Type: LinkCausedBy, ParentID: "42", ParentChannel: "C1"})
+	u.ticketStore.InsertTicket(ctx, "42", "C1", Ticket{Key: "OPS-1", Status: "Done", Resolved: true})
+	u.assignments.InsertAssignment(ctx, Assignment{IncidentID: "42", Channel: "C1", Assignee: "U0ALICE01"})
+	u.customerFacing.InsertCustomerFacing(ctx, "42", "C1", "U0ALICE01", "Checkout is slow")
+
+	dir := t.TempDir()
+	count, err := u.ArchiveIncidents(ctx, RetentionPolicy{Days: 30, Dir: dir}, now)
+	if err != nil || count != 1 {
+		t.Fatalf("ArchiveIncidents = %d, %v; want 1 (incident 43 has an open action item)", count, err)
+	}
+
+	if _, err := repo.GetNewRelicIncidentByID(ctx, "43", "C1"); err != nil {
+		t.Errorf("incident 43 with an open action item was archived: %v", err)
+	}
+	assertIncidentData(t, u, 0)
+	if links, _ := u.links.GetIncidentLinks(ctx, "44", "C1"); len(links) != 0 {
+		t.Errorf("link of the open child to the archived parent kept: %+v", links)
+	}
+
+	restored, err := u.RestoreIncident(ctx, dir, "42")
+	if err != nil || len(restored) != 1 || restored[0].MessageTimestamp != "1700000000.000100" {
+		t.Fatalf("RestoreIncident = %+v, %v", restored, err)
+	}
+	assertIncidentData(t, u, 1)
+	if links, _ := u.links.GetIncidentLinks(ctx, "44", "C1"); len(links) != 1 {
+		t.Errorf("link of the open child not restored: %+v", links)
+	}
+}
+
+// assertIncidentData checks that incident 42 in C1 has want of each record
+// the retention policy archives with it.
+func assertIncidentData(t *testing.T, u *UseCase, want int) {
+	t.Helper()
+	ctx := context.Background()
+
+	count := func(found bool) int {
+		if found {
+			return 1
+		}
+		return 0
+	}
+	events, _ := u.events.GetIncidentEvents(ctx, "42", "C1")
+	items, _ := u.actionItems.GetActionItemsByIncident(ctx, "42", "C1")
+	_, children, _ := u.GetIncidentLinks(ctx, "42", "C1")
+	_, ticketErr := u.ticketStore.GetTicket(ctx, "42", "C1")
+	assignments, _ := u.assignments.GetAssignments(ctx, "42", "C1")
+	flagged, _ := u.customerFacing.IsCustomerFacing(ctx, "42", "C1")
+
+	for store, got := range map[string]int{
+		"events":               len(events),
+		"action items":         len(items),
+		"child links":          len(children),
+		"ticket":               count(ticketErr == nil),
+		"assignments":          len(assignments),
+		"customer-facing flag": count(flagged),
+	} {
+		if got != want {
+			t.Errorf("incident 42 has %d %s, want %d", got, store, want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// EventDiagnostics is recorded when the diagnostics of an incident have run.
+const EventDiagnostics = "diagnostics"
+
+const (
+	// diagnosticTimeout bounds a diagnostic without timeout_seconds.
+	diagnosticTimeout = 10 * time.Second
+	// diagnosticOutputLimit is the most output kept from one diagnostic.
+	diagnosticOutputLimit = 1000
+	// diagnosticsRunTimeout bounds all diagnostics of one incident, including the reply.
+	diagnosticsRunTimeout = 2 * time.Minute
+	// defaultDiagnosticsConcurrency is how many incidents run diagnostics at once.
+	defaultDiagnosticsConcurrency = 4
+)
+
+// WithDiagnosticsConcurrency limits how many incidents run their diagnostics
+// at once. Diagnostics of incidents opened while n are running are skipped.
+func WithDiagnosticsConcurrency(n int) Option {
+	return func(u *UseCase) {
+		if n > 0 {
+			u.diagnostics = make(chan struct{}, n)
+		}
+	}
+}
+
+// Runbook links an alert condition to its operating procedure.
+type Runbook struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Diagnostic is a check run when an incident of the condition opens: either a
+// command (executed without a shell) or an HTTP GET against URL.
+// The command gets DIARY_INCIDENT_ID, DIARY_CHANNEL and DIARY_CONDITION_ID in
+// its environment.
+type Diagnostic struct {
+	Name           string   `json:"name"`
+	Command        []string `json:"command,omitempty"`
+	URL            string   `json:"url,omitempty"`
+	ExpectStatus   int      `json:"expect_status,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+// DiagnosticResult is the outcome of one diagnostic.
+type DiagnosticResult struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Output   string        `json:"output"`
+	Duration time.Duration `json:"duration"`
+}
+
+// blockRepository is implemented by repositories that can reply with Block Kit blocks.
+type blockRepository interface {
+	ReplyBlocksInThread(ctx context.Context, channel, messageTimestamp, fallback string, blocks ...slack.Block) (string, error)
+}
+
+func (r Runbook) validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("runbook %q: url must be an absolute http(s) URL", r.URL)
+	}
+
+	return nil
+}
+
+func (d Diagnostic) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("diagnostic name is required")
+	}
+	if (len(d.Command) == 0) == (d.URL == "") {
+		return fmt.Errorf("diagnostic %q: exactly one of command or url is required", d.Name)
+	}
+	if d.URL != "" {
+		if err := (Runbook{URL: d.URL}).validate(); err != nil {
+			return fmt.Errorf("diagnostic %q: %w", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RunbookButtons renders the runbooks of a condition as link buttons.
+func RunbookButtons(runbooks []Runbook) []slack.BlockElement {
+	buttons := []slack.BlockElement{}
+	for i, runbook := range runbooks {
+		button := slack.NewButtonBlockElement(fmt.Sprintf("runbook_%d", i), runbook.URL, slack.NewTextBlockObject(slack.PlainTextType, ":book: "+runbookTitle(runbook), true, false))
+		button.URL = runbook.URL
+		buttons = append(buttons, button)
+	}
+
+	return buttons
+}
+
+// postIncidentActions replies in a new incident thread with the "Take
+// ownership" button and the runbooks of its condition. Repositories without
+// Block Kit support get the runbooks as plain links.
+func (u *UseCase) postIncidentActions(ctx context.Context, condition AlertCondition, incident entitySlack.Incident) {
+	links := []string{}
+	for _, runbook := range condition.Runbooks {
+		links = append(links, fmt.Sprintf("<%s|%s>", runbook.URL, runbookTitle(runbook)))
+	}
+	fallback := ":book: " + strings.Join(links, " | ")
+
+	var err error
+	if blocks, ok := u.baseRepo.(blockRepository); ok {
+		// Block replies bypass the decorators, so send coalesced replies first
+		u.sendNow(incident.Channel, incident.MessageTimestamp)
+		buttons := append([]slack.BlockElement{OwnershipButton()}, RunbookButtons(condition.Runbooks)...)
+		if len(links) == 0 {
+			fallback = "Take ownership of this incident"
+		}
+		_, err = blocks.ReplyBlocksInThread(ctx, incident.Channel, incident.MessageTimestamp, fallback, slack.NewActionBlock("incident_actions", buttons...))
+	} else if len(links) > 0 {
+		_, _, err = u.slackRepo.ReplyMessageInThread(ctx, incident.Channel, fallback, u.GetColorStr(incident.Status, incident.Severity), incident.MessageTimestamp, incident.URL)
+	}
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", incident.Channel, err)
+	}
+}
+
+func runbookTitle(runbook Runbook) string {
+	if runbook.Title == "" {
+		return "Runbook"
+	}
+
+	return runbook.Title
+}
+
+// RunDiagnostics runs every diagnostic of condition for incident, in order.
+func (u *UseCase) RunDiagnostics(ctx context.Context, condition AlertCondition, incident entitySlack.Incident) []DiagnosticResult {
+	results := []DiagnosticResult{}
+	for _, diagnostic := range condition.Diagnostics {
+		results = append(results, runDiagnostic(ctx, diagnostic, incident))
+	}
+
+	return results
+}
+
+// startDiagnostics runs the diagnostics of condition in the background when a
+// slot is free, so alert storms cannot pile up commands and HTTP checks.
+func (u *UseCase) startDiagnostics(ctx context.Context, condition AlertCondition, incident entitySlack.Incident) {
+	if len(condition.Diagnostics) == 0 {
+		return
+	}
+
+	select {
+	case u.diagnostics <- struct{}{}:
+	default:
+		log.Errorf("Skip diagnostics of incident %s because %d are already running", incident.IncidentID, cap(u.diagnostics))
+		return
+	}
+
+	go func() {
+		defer func() { <-u.diagnostics }()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsRunTimeout)
+		defer cancel()
+		u.postDiagnostics(ctx, condition, incident)
+	}()
+}
+
+// postDiagnostics runs the diagnostics of condition and replies with their results.
+func (u *UseCase) postDiagnostics(ctx context.Context, condition AlertCondition, incident entitySlack.Incident) {
+	if len(condition.Diagnostics) == 0 {
+		return
+	}
+
+	results := u.RunDiagnostics(ctx, condition, incident)
+	payload, _ := json.Marshal(results)
+
+	failed := 0
+	lines := []string{"*Diagnostics*"}
+	for _, result := range results {
+		icon := ":white_check_mark:"
+		if !result.OK {
+			icon = ":x:"
+			failed++
+		}
+		lines = append(lines, fmt.Sprintf("%s *%s* (%s)", icon, result.Name, result.Duration.Round(time.Millisecond)))
+		if result.Output != "" {
+			lines = append(lines, "```"+result.Output+"```")
+		}
+	}
+
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: incident.IncidentID,
+		Channel:    incident.Channel,
+		Type:       EventDiagnostics,
+		Actor:      "diary",
+		Detail:     fmt.Sprintf("%d of %d checks failed", failed, len(results)),
+		Payload:    payload,
+	})
+
+	_, _, err := u.slackRepo.ReplyMessageInThread(ctx, incident.Channel, strings.Join(lines, "\n"), u.GetColorStr(incident.Status, incident.Severity), incident.MessageTimestamp, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", incident.Channel, err)
+	}
+}
+
+func runDiagnostic(ctx context.Context, diagnostic Diagnostic, incident entitySlack.Incident) DiagnosticResult {
+	timeout := diagnosticTimeout
+	if diagnostic.TimeoutSeconds > 0 {
+		timeout = time.Duration(diagnostic.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var (
+		output string
+		err    error
+	)
+	if len(diagnostic.Command) > 0 {
+		output, err = runCommandCheck(ctx, diagnostic, incident)
+	} else {
+		output, err = runHTTPCheck(ctx, diagnostic)
+	}
+
+	result := DiagnosticResult{
+		Name:     diagnostic.Name,
+		OK:       err == nil,
+		Output:   strings.TrimSpace(output),
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Output = strings.TrimSpace(result.Output + "\n" + err.Error())
+	}
+	result.Output = truncateBytes(result.Output, diagnosticOutputLimit)
+
+	return result
+}
+
+// truncateBytes cuts s to at most limit bytes plus "...", backing up to a
+// rune boundary so a multi-byte character is never split.
+func truncateBytes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+func runCommandCheck(ctx context.Context, diagnostic Diagnostic, incident entitySlack.Incident) (string, error) {
+	cmd := exec.CommandContext(ctx, diagnostic.Command[0], diagnostic.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"DIARY_INCIDENT_ID="+incident.IncidentID,
+		"DIARY_CHANNEL="+incident.Channel,
+		"DIARY_CONDITION_ID="+strconv.Itoa(incident.ConditionID),
+	)
+
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func runHTTPCheck(ctx context.Context, diagnostic Diagnostic) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, diagnostic.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, diagnosticOutputLimit))
+	output := fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, body)
+
+	expect := diagnostic.ExpectStatus
+	if expect == 0 {
+		expect = http.StatusOK
+	}
+	if resp.StatusCode != expect {
+		return output, fmt.Errorf("expected status %d", expect)
+	}
+
+	return output, nil
+}
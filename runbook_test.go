@@ -0,0 +1,105 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+// diagnosticReplies returns the thread replies carrying diagnostics results.
+func diagnosticReplies(repo *MemoryRepository) []FakeSlackCall {
+	replies := []FakeSlackCall{}
+	for _, call := range repo.Calls("ReplyMessageInThread") {
+		if strings.HasPrefix(call.Text, "*Diagnostics*") {
+			replies = append(replies, call)
+		}
+	}
+	return replies
+}
+
+func TestDiagnosticsAreBounded(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	cfg := Config{}
+	cfg.Slack.NewRelic = []AlertCondition{{ID: 7, Name: "CPU high", Diagnostics: []Diagnostic{{Name: "health", URL: server.URL}}}}
+	store, err := NewConfigStore(cfg)
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+	repo := NewMemoryRepository()
+	u := New(repo, WithConfig(store), WithDiagnosticsConcurrency(1))
+
+	u.ProcessIncident(ctx, testPayload("1", "open"))
+	// The only slot is taken, so the second incident skips its diagnostics
+	u.ProcessIncident(ctx, testPayload("2", "open"))
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(diagnosticReplies(repo)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	replies := diagnosticReplies(repo)
+	if len(replies) != 1 {
+		t.Fatalf("diagnostics replies = %+v, want one", replies)
+	}
+	first, _ := repo.GetNewRelicIncidentByID(ctx, "1", "C1")
+	if replies[0].ThreadTimestamp != first.MessageTimestamp {
+		t.Errorf("diagnostics replied to %s, want incident 1 thread %s", replies[0].ThreadTimestamp, first.MessageTimestamp)
+	}
+
+	// The slot is released once the run is done
+	for len(u.diagnostics) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	u.ProcessIncident(ctx, testPayload("3", "open"))
+	for len(diagnosticReplies(repo)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(diagnosticReplies(repo)); n != 2 {
+		t.Errorf("%d diagnostics replies after the slot was released, want 2", n)
+	}
+}
+
+func TestDiagnosticOutputKeepsRunes(t *testing.T) {
+	// The 1000 byte limit falls in the middle of a two-byte character
+	body := "x" + strings.Repeat("é", diagnosticOutputLimit)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	result := runDiagnostic(context.Background(), Diagnostic{Name: "health", URL: server.URL}, entitySlack.Incident{IncidentID: "42", Channel: "C1"})
+	if !utf8.ValidString(result.Output) {
+		t.Errorf("output is not valid UTF-8: %q", result.Output[len(result.Output)-10:])
+	}
+	if !strings.HasSuffix(result.Output, "é...") || len(result.Output) > diagnosticOutputLimit+len("...") {
+		t.Errorf("output of %d bytes ends in %q", len(result.Output), result.Output[len(result.Output)-10:])
+	}
+
+	for _, tc := range []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc..."},
+		{"aé", 2, "a..."},
+		{"aé", 3, "aé"},
+		{"日本", 4, "日..."},
+	} {
+		if got := truncateBytes(tc.s, tc.limit); got != tc.want {
+			t.Errorf("truncateBytes(%q, %d) = %q, want %q", tc.s, tc.limit, got, tc.want)
+		}
+	}
+}
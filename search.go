@@ -0,0 +1,216 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+)
+
+// ErrSearchUnsupported is returned when the repository cannot query sets of incidents.
+var ErrSearchUnsupported = errors.New("incident search is not supported by the repository")
+
+// IncidentQuery filters incidents. Zero values match everything; Labels
+// entries are key=value pairs that must all match.
+type IncidentQuery struct {
+	Status    []string
+	Channel   string
+	Vendor    string
+	Severity  string
+	Owner     string
+	Labels    map[string]string
+	RootCause string
+	From      time.Time
+	To        time.Time
+	// Unposted matches only incidents stored without a Slack message
+	Unposted bool
+	Limit    int
+	Offset   int
+}
+
+// Match reports whether incident satisfies every filter of the query.
+func (q IncidentQuery) Match(incident entitySlack.Incident) bool {
+	if len(q.Status) > 0 && !containsFold(q.Status, incident.Status) {
+		return false
+	}
+	if q.Channel != "" && q.Channel != incident.Channel {
+		return false
+	}
+	if q.Unposted && incident.MessageTimestamp != "" {
+		return false
+	}
+	if q.Vendor != "" && !strings.EqualFold(q.Vendor, incident.GeneratedBy) {
+		return false
+	}
+	if q.Severity != "" && NormalizeSeverity(q.Severity) != NormalizeSeverity(incident.Severity) {
+		return false
+	}
+	if q.Owner != "" && !strings.EqualFold(q.Owner, incident.Owner) {
+		return false
+	}
+	if q.RootCause != "" && !strings.EqualFold(q.RootCause, incident.RootCause) {
+		return false
+	}
+	if !q.From.IsZero() && incident.StartTime.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !incident.StartTime.Before(q.To) {
+		return false
+	}
+	if len(q.Labels) > 0 {
+		labels := map[string]string{}
+		json.Unmarshal([]byte(incident.Labels), &labels)
+		for k, v := range q.Labels {
+			if labels[k] != v {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Normalized applies the default and maximum page size to the query.
+func (q IncidentQuery) Normalized() IncidentQuery {
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	return q
+}
+
+// incidentSearcher is implemented by repositories that can query sets of incidents.
+// Results are ordered by start time, newest first, and total counts every match.
+type incidentSearcher interface {
+	SearchIncidents(ctx context.Context, query IncidentQuery) ([]entitySlack.Incident, int, error)
+}
+
+// IncidentView is the JSON representation of an incident served by the API.
+type IncidentView struct {
+	IncidentID       string            `json:"incident_id"`
+	ConditionID      int               `json:"condition_id"`
+	Name             string            `json:"name"`
+	URL              string            `json:"url"`
+	Description      string            `json:"description"`
+	Owner            string            `json:"owner"`
+	Vendor           string            `json:"vendor"`
+	Status           string            `json:"status"`
+	Severity         string            `json:"severity"`
+	RootCause        string            `json:"root_cause"`
+	Channel          string            `json:"channel"`
+	Labels           map[string]string `json:"labels"`
+	UserACK          string            `json:"user_ack"`
+	UserACKName      string            `json:"user_ack_name,omitempty"`
+	MessageTimestamp string            `json:"message_ts"`
+	StartTime        time.Time         `json:"start_time"`
+	RecoverTime      *time.Time        `json:"recover_time"`
+	TTRSeconds       int64             `json:"ttr_seconds,omitempty"`
+}
+
+// incidentView is NewIncidentView with the acknowledging user resolved to a display name.
+func (u *UseCase) incidentView(ctx context.Context, i entitySlack.Incident) IncidentView {
+	v := NewIncidentView(i)
+	if v.UserACK != "" {
+		v.UserACKName = u.displayName(ctx, v.UserACK)
+	}
+
+	return v
+}
+
+func NewIncidentView(i entitySlack.Incident) IncidentView {
+	v := IncidentView{
+		IncidentID:       i.IncidentID,
+		ConditionID:      i.ConditionID,
+		Name:             i.Name,
+		URL:              i.URL,
+		Description:      i.Description,
+		Owner:            i.Owner,
+		Vendor:           i.GeneratedBy,
+		Status:           i.Status,
+		Severity:         i.Severity,
+		RootCause:        i.RootCause,
+		Channel:          i.Channel,
+		Labels:           map[string]string{},
+		UserACK:          i.UserACK,
+		MessageTimestamp: i.MessageTimestamp,
+		StartTime:        i.StartTime,
+	}
+	if v.RootCause == "null" {
+		v.RootCause = ""
+	}
+	json.Unmarshal([]byte(i.Labels), &v.Labels)
+
+	if !i.RecoverTime.IsZero() {
+		recoverTime := i.RecoverTime
+		v.RecoverTime = &recoverTime
+		v.TTRSeconds = int64(i.RecoverTime.Sub(i.StartTime).Seconds())
+	}
+
+	return v
+}
+
+// SearchIncidents returns one page of incidents matching query and the total match count.
+func (u *UseCase) SearchIncidents(ctx context.Context, query IncidentQuery) ([]entitySlack.Incident, int, error) {
+	searcher, ok := u.baseRepo.(incidentSearcher)
+	if !ok {
+		return nil, 0, ErrSearchUnsupported
+	}
+
+	return searcher.SearchIncidents(ctx, query.Normalized())
+}
+
+func (m *MemoryRepository) SearchIncidents(ctx context.Context, query IncidentQuery) ([]entitySlack.Incident, int, error) {
+	m.mu.RLock()
+	err := m.fail("SearchIncidents")
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matches := []entitySlack.Incident{}
+	for _, incident := range m.Incidents() {
+		if query.Match(incident) {
+			matches = append(matches, incident)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].StartTime.After(matches[j].StartTime)
+	})
+
+	query = query.Normalized()
+	total := len(matches)
+	if query.Offset >= total {
+		return []entitySlack.Incident{}, total, nil
+	}
+	end := query.Offset + query.Limit
+	if end > total {
+		end = total
+	}
+
+	return matches[query.Offset:end], total, nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
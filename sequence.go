@@ -0,0 +1,18 @@
+package slack
+
+// stateRank orders the NewRelic incident lifecycle. NewRelic never moves an
+// incident backwards, so a lower ranked state after a higher one is a stale
+// delivery rather than a reopen.
+var stateRank = map[string]int{
+	"open":         1,
+	"acknowledged": 2,
+	"closed":       3,
+}
+
+// isStale reports whether a payload with the given state would regress an
+// incident currently in currentState. Payloads carry no event time, so
+// deliveries are ordered by the lifecycle alone.
+func isStale(currentState, state string) bool {
+	current, next := stateRank[currentState], stateRank[state]
+	return current > 0 && next > 0 && next < current
+}
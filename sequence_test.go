@@ -0,0 +1,75 @@
+package slack
+
+import (
+	"context"
+	"testing"
+)
+
+// permutations returns every ordering of states.
+func permutations(states []string) [][]string {
+	if len(states) <= 1 {
+		return [][]string{append([]string(nil), states...)}
+	}
+
+	orders := [][]string{}
+	for i, state := range states {
+		rest := append(append([]string(nil), states[:i]...), states[i+1:]...)
+		for _, order := range permutations(rest) {
+			orders = append(orders, append([]string{state}, order...))
+		}
+	}
+	return orders
+}
+
+func TestProcessIncidentOutOfOrder(t *testing.T) {
+	for _, order := range permutations([]string{"open", "acknowledged", "closed"}) {
+		t.Run(order[0]+"-"+order[1]+"-"+order[2], func(t *testing.T) {
+			ctx := context.Background()
+			u, _ := newTestUseCase(t)
+
+			rank := 0
+			for _, state := range order {
+				incident, err := u.ProcessIncident(ctx, testPayload("42", state))
+				if err != nil {
+					t.Fatalf("ProcessIncident(%s): %v", state, err)
+				}
+				if stateRank[incident.Status] < rank {
+					t.Fatalf("status regressed to %s after %s", incident.Status, state)
+				}
+				rank = stateRank[incident.Status]
+			}
+
+			incident, err := u.slackRepo.GetNewRelicIncidentByID(ctx, "42", "C1")
+			if err != nil || incident.Status != "closed" {
+				t.Fatalf("final incident = %+v, %v; want closed", incident, err)
+			}
+
+			// Every delivery stays in the timeline, the regressing ones marked stale
+			received := 0
+			timeline, _ := u.GetIncidentTimeline(ctx, "42", "C1")
+			for _, event := range timeline {
+				if event.Type != EventReceived {
+					continue
+				}
+				received++
+				if want := stateRank[event.State] < stateRank[maxState(order[:received])]; event.Stale != want {
+					t.Errorf("received %s stale = %v, want %v", event.State, event.Stale, want)
+				}
+			}
+			if received != len(order) {
+				t.Errorf("%d received events, want %d", received, len(order))
+			}
+		})
+	}
+}
+
+// maxState returns the furthest state of the lifecycle among states.
+func maxState(states []string) string {
+	furthest := ""
+	for _, state := range states {
+		if stateRank[state] > stateRank[furthest] {
+			furthest = state
+		}
+	}
+	return furthest
+}
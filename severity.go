@@ -0,0 +1,243 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Severity is the normalized incident severity, SEV1 being the most severe.
+type Severity string
+
+const (
+	SEV1 Severity = "SEV1"
+	SEV2 Severity = "SEV2"
+	SEV3 Severity = "SEV3"
+	SEV4 Severity = "SEV4"
+)
+
+// DefaultSeverity is used when neither a rule nor the vendor priority classifies an incident.
+const DefaultSeverity = SEV3
+
+// resolvedColor is the attachment color of closed incidents regardless of severity.
+const resolvedColor = "00BF85"
+
+// severityAliases maps vendor severities and priorities to the normalized model.
+var severityAliases = map[string]Severity{
+	"sev1":          SEV1,
+	"p1":            SEV1,
+	"1":             SEV1,
+	"critical":      SEV1,
+	"emergency":     SEV1,
+	"disaster":      SEV1,
+	"sev2":          SEV2,
+	"p2":            SEV2,
+	"2":             SEV2,
+	"high":          SEV2,
+	"error":         SEV2,
+	"sev3":          SEV3,
+	"p3":            SEV3,
+	"3":             SEV3,
+	"medium":        SEV3,
+	"moderate":      SEV3,
+	"warning":       SEV3,
+	"sev4":          SEV4,
+	"p4":            SEV4,
+	"p5":            SEV4,
+	"4":             SEV4,
+	"5":             SEV4,
+	"low":           SEV4,
+	"info":          SEV4,
+	"informational": SEV4,
+}
+
+// severityLabels are the labels whose value is read as a vendor priority
+// when the payload severity is missing or unknown.
+var severityLabels = []string{"severity", "priority"}
+
+var severityStyles = map[Severity]struct{ color, emoji string }{
+	SEV1: {"D00000", ":rotating_light:"},
+	SEV2: {"FF6D00", ":red_circle:"},
+	SEV3: {"F2C744", ":large_orange_diamond:"},
+	SEV4: {"439FE0", ":large_blue_circle:"},
+}
+
+// ParseSeverity normalizes a severity, vendor severity or priority, ignoring case.
+func ParseSeverity(s string) (Severity, bool) {
+	severity, ok := severityAliases[strings.ToLower(strings.TrimSpace(s))]
+	return severity, ok
+}
+
+// SeverityAliases returns the lower-case values, including its own name, that
+// ParseSeverity maps to s.
+func SeverityAliases(s Severity) []string {
+	aliases := []string{}
+	for alias, severity := range severityAliases {
+		if severity == s {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// KnownSeverities returns the lower-case values ParseSeverity knows, so
+// stores can find values that NormalizeSeverity maps to DefaultSeverity.
+func KnownSeverities() []string {
+	aliases := []string{}
+	for alias := range severityAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// NormalizeSeverity is ParseSeverity falling back to DefaultSeverity, so
+// incidents stored before classification still render and compare.
+func NormalizeSeverity(s string) Severity {
+	if severity, ok := ParseSeverity(s); ok {
+		return severity
+	}
+
+	return DefaultSeverity
+}
+
+// Rank orders severities: SEV1 ranks 4 and SEV4 ranks 1.
+func (s Severity) Rank() int {
+	switch s {
+	case SEV1:
+		return 4
+	case SEV2:
+		return 3
+	case SEV3:
+		return 2
+	case SEV4:
+		return 1
+	}
+
+	return 0
+}
+
+// Color is the Slack attachment color of an unresolved incident.
+func (s Severity) Color() string {
+	return severityStyles[NormalizeSeverity(string(s))].color
+}
+
+// Emoji is the Slack emoji shown next to the severity.
+func (s Severity) Emoji() string {
+	return severityStyles[NormalizeSeverity(string(s))].emoji
+}
+
+// SeverityRule classifies incidents matching all of its set fields. Priority
+// is compared with the vendor severity and Labels with the incident labels.
+type SeverityRule struct {
+	Vendor      string            `json:"vendor,omitempty"`
+	ConditionID int               `json:"alert_condition_id,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Severity    Severity          `json:"severity"`
+}
+
+func (r SeverityRule) validate() error {
+	if r.Severity.Rank() == 0 {
+		return fmt.Errorf("severity rule: severity %q is not one of SEV1-SEV4", r.Severity)
+	}
+	if r.Vendor == "" && r.ConditionID == 0 && r.Priority == "" && len(r.Labels) == 0 {
+		return fmt.Errorf("severity rule %s: no vendor, alert_condition_id, priority or labels to match", r.Severity)
+	}
+
+	return nil
+}
+
+func (r SeverityRule) matches(data entitySlack.NewRelicReplyThread, labels map[string]string) bool {
+	if r.Vendor != "" && !strings.EqualFold(r.Vendor, data.GetVendor()) {
+		return false
+	}
+	if r.ConditionID != 0 && r.ConditionID != data.GetConditionID() {
+		return false
+	}
+	if r.Priority != "" && !strings.EqualFold(r.Priority, data.GetSeverity()) {
+		return false
+	}
+	for k, v := range r.Labels {
+		if !strings.EqualFold(labels[k], v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ClassifySeverity maps an incoming incident to a severity: the first
+// matching rule wins, then the vendor severity, then the severity or
+// priority label, then DefaultSeverity.
+func (c *Config) ClassifySeverity(data entitySlack.NewRelicReplyThread) Severity {
+	labels := map[string]string{}
+	json.Unmarshal([]byte(data.GetLabels()), &labels)
+
+	for _, rule := range c.SeverityRules {
+		if rule.matches(data, labels) {
+			return rule.Severity
+		}
+	}
+
+	if severity, ok := ParseSeverity(data.GetSeverity()); ok {
+		return severity
+	}
+	for _, key := range severityLabels {
+		if severity, ok := ParseSeverity(labels[key]); ok {
+			return severity
+		}
+	}
+
+	return DefaultSeverity
+}
+
+// severityRepository is implemented by repositories that can change the
+// severity of a stored incident.
+type severityRepository interface {
+	UpdateNewRelicIncidentSeverityByID(ctx context.Context, severity, incidentID, channel string) error
+}
+
+// escalate records a delivery that raises the severity of a known incident
+// as an escalation, and stores the new severity when the repository can.
+func (u *UseCase) escalate(ctx context.Context, data entitySlack.NewRelicReplyThread, incident entitySlack.Incident) {
+	if incident.IncidentID == "" {
+		return
+	}
+	previous, severity := NormalizeSeverity(incident.Severity), u.severityOf(data)
+	if severity.Rank() <= previous.Rank() {
+		return
+	}
+
+	if repo, ok := u.baseRepo.(severityRepository); ok {
+		if err := repo.UpdateNewRelicIncidentSeverityByID(ctx, string(severity), incident.IncidentID, incident.Channel); err != nil {
+			log.Errorf("Error store incident severity to database: %s", err)
+		}
+	}
+	if err := u.RecordEscalation(ctx, incident.IncidentID, incident.Channel, data.GetVendor(), fmt.Sprintf("%s to %s", previous, severity)); err != nil {
+		log.Errorf("Error store incident event to database: %s", err)
+	}
+}
+
+// severityOf classifies data with the active configuration.
+func (u *UseCase) severityOf(data entitySlack.NewRelicReplyThread) Severity {
+	return u.config.Load().ClassifySeverity(data)
+}
+
+// withSeverity adds the severity line above the status of a rendered incident message.
+func withSeverity(message, severity string) string {
+	s := NormalizeSeverity(severity)
+	line := fmt.Sprintf("*Severity* : %s *%s*\n", s.Emoji(), s)
+	if i := strings.Index(message, "*Current Status*"); i >= 0 {
+		return message[:i] + line + message[i:]
+	}
+	return line + message
+}
@@ -0,0 +1,75 @@
+package slack
+
+import (
+	"context"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// applyState moves a stored incident to state outside of a vendor webhook
+// (e.g. resolved together with its parent), going through the same steps
+// ProcessIncident uses: store the status, record the event, update the
+// parent message and reply in its thread.
+func (u *UseCase) applyState(ctx context.Context, incident entitySlack.Incident, state, actor, note string) (entitySlack.Incident, error) {
+	var recoverTime time.Time
+	if state == "closed" || state == "acknowledged" {
+		recoverTime = time.Now().Local()
+	}
+
+	if err := u.slackRepo.UpdateNewRelicIncidentStatusByID(ctx, state, incident.MessageTimestamp, incident.Channel, incident.IncidentID, recoverTime); err != nil {
+		return incident, err
+	}
+
+	if incident.Status != state {
+		u.recordEvent(ctx, IncidentEvent{
+			IncidentID:    incident.IncidentID,
+			Channel:       incident.Channel,
+			Type:          EventStateChanged,
+			State:         state,
+			PreviousState: incident.Status,
+			Actor:         actor,
+			Detail:        note,
+		})
+	}
+
+	updated, err := u.refreshIncident(ctx, incident.IncidentID, incident.Channel, note)
+	if err != nil {
+		return incident, err
+	}
+
+	if state == "closed" && incident.Status != "closed" {
+		u.resolveChildren(ctx, updated)
+		u.resolveTicket(ctx, updated)
+	}
+	u.publishStatusPage(ctx, updated.IncidentID, updated.Channel)
+
+	return updated, nil
+}
+
+// refreshIncident re-renders the parent message of a stored incident and,
+// when note is set, replies with it in the thread.
+func (u *UseCase) refreshIncident(ctx context.Context, incidentID, channel, note string) (entitySlack.Incident, error) {
+	updated, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incidentID, channel)
+	if err != nil {
+		return updated, err
+	}
+
+	// Update Slack Message
+	title := u.GetTitle(updated.GeneratedBy, updated.Status, updated.Name, updated.URL)
+	message := withAssignee(withSeverity(u.GetMessageString(updated.Description, updated.Status, updated.StartTime, updated.RecoverTime), updated.Severity), u.currentAssignee(ctx, updated.IncidentID, updated.Channel))
+	_, _, err = u.slackRepo.UpdateMessage(ctx, updated.Channel, title+message, u.GetColorStr(updated.Status, updated.Severity), updated.MessageTimestamp, updated.GeneratedBy, updated.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", updated.Channel, err)
+	}
+
+	if note != "" {
+		_, _, err = u.slackRepo.ReplyMessageInThread(ctx, updated.Channel, note, u.GetColorStr(updated.Status, updated.Severity), updated.MessageTimestamp, updated.URL)
+		if err != nil {
+			log.Errorf("Failed send slack message to channel %s because: %s", updated.Channel, err)
+		}
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,500 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// EventCustomerFacing is recorded when an incident is flagged for the status page.
+const EventCustomerFacing = "customer_facing"
+
+// Block IDs of the status page inputs in the Ack form.
+const (
+	CustomerFacingBlock        = "customer_facing"
+	CustomerFacingSummaryBlock = "customer_facing_summary"
+)
+
+// Component statuses published on the status page.
+const (
+	ComponentOperational = "operational"
+	ComponentDegraded    = "degraded_performance"
+	ComponentMajorOutage = "major_outage"
+)
+
+const (
+	// statusPageHistoryDays is how long resolved incidents stay on the status page.
+	statusPageHistoryDays = 14
+	// statusPageComponentLabel is the incident label naming the affected component.
+	statusPageComponentLabel = "component"
+	// Published for incidents without a summary or a component label, which
+	// would otherwise expose internal alert condition names.
+	statusPageDefaultSummary   = "Service disruption"
+	statusPageDefaultComponent = "Platform"
+)
+
+// StatusPage publishes customer-facing incidents as a static site: index.html
+// and status.json are regenerated in Dir on every change.
+type StatusPage struct {
+	Dir   string
+	Title string
+}
+
+// WithStatusPage publishes customer-facing incidents to page.
+func WithStatusPage(page StatusPage) Option {
+	return func(u *UseCase) {
+		u.statusPage = &page
+	}
+}
+
+// customerFacingRepository stores which incidents are published on the
+// status page and the summary they are published with. Flagging an incident
+// again keeps the first actor and replaces the summary unless it is empty.
+type customerFacingRepository interface {
+	InsertCustomerFacing(ctx context.Context, incidentID, channel, actor, summary string) error
+	IsCustomerFacing(ctx context.Context, incidentID, channel string) (bool, error)
+	GetCustomerFacingSummary(ctx context.Context, incidentID, channel string) (string, error)
+	DeleteCustomerFacing(ctx context.Context, incidentID, channel string) error
+}
+
+// WithCustomerFacingStore replaces the default in-memory customer-facing flags,
+// e.g. with a durable repository.SQLRepository.
+func WithCustomerFacingStore(store customerFacingRepository) Option {
+	return func(u *UseCase) {
+		u.customerFacing = store
+	}
+}
+
+// MemoryCustomerFacing is an in-process customerFacingRepository.
+type MemoryCustomerFacing struct {
+	mu      sync.RWMutex
+	flagged map[incidentKey]customerFacingFlag
+}
+
+type customerFacingFlag struct {
+	actor   string
+	summary string
+}
+
+func NewMemoryCustomerFacing() *MemoryCustomerFacing {
+	return &MemoryCustomerFacing{
+		flagged: map[incidentKey]customerFacingFlag{},
+	}
+}
+
+// InsertCustomerFacing flags an incident; flagging it again keeps the first
+// actor and replaces the summary unless it is empty.
+func (m *MemoryCustomerFacing) InsertCustomerFacing(ctx context.Context, incidentID, channel, actor, summary string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := incidentKey{incidentID, channel}
+	flag, ok := m.flagged[key]
+	if !ok {
+		flag.actor = actor
+	}
+	if summary != "" {
+		flag.summary = summary
+	}
+	m.flagged[key] = flag
+	return nil
+}
+
+func (m *MemoryCustomerFacing) IsCustomerFacing(ctx context.Context, incidentID, channel string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.flagged[incidentKey{incidentID, channel}]
+	return ok, nil
+}
+
+func (m *MemoryCustomerFacing) GetCustomerFacingSummary(ctx context.Context, incidentID, channel string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	flag, ok := m.flagged[incidentKey{incidentID, channel}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return flag.summary, nil
+}
+
+func (m *MemoryCustomerFacing) DeleteCustomerFacing(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.flagged, incidentKey{incidentID, channel})
+	return nil
+}
+
+// StatusFeed is the status.json document.
+type StatusFeed struct {
+	Title       string            `json:"title"`
+	Status      string            `json:"status"`
+	Components  []ComponentStatus `json:"components"`
+	Incidents   []StatusIncident  `json:"incidents"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// ComponentStatus is the current state of one component.
+type ComponentStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// StatusIncident is a customer-facing incident and its public updates.
+type StatusIncident struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Component  string         `json:"component"`
+	Status     string         `json:"status"`
+	StartedAt  time.Time      `json:"started_at"`
+	ResolvedAt *time.Time     `json:"resolved_at,omitempty"`
+	Updates    []StatusUpdate `json:"updates"`
+}
+
+// StatusUpdate is one public update of an incident, newest first.
+type StatusUpdate struct {
+	Status string    `json:"status"`
+	Body   string    `json:"body"`
+	At     time.Time `json:"at"`
+}
+
+// CustomerFacingBlocks returns the status page inputs appended to the Ack form.
+func CustomerFacingBlocks() []slack.Block {
+	option := slack.NewOptionBlockObject("yes", slack.NewTextBlockObject(slack.PlainTextType, "Publish on the status page", false, false), nil)
+	checkbox := slack.NewInputBlock(
+		CustomerFacingBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Customer facing", false, false),
+		nil,
+		slack.NewCheckboxGroupsBlockElement(CustomerFacingBlock, option),
+	)
+	checkbox.Optional = true
+
+	summary := slack.NewInputBlock(
+		CustomerFacingSummaryBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Status page summary", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Shown to customers instead of the alert name", false, false),
+		slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "e.g. Checkout is slow for some customers", false, false), CustomerFacingSummaryBlock),
+	)
+	summary.Optional = true
+
+	return []slack.Block{checkbox, summary}
+}
+
+// SetCustomerFacing flags an incident for the status page with the summary
+// customers see, and republishes it. Flagging it again with a summary
+// replaces the summary. It returns ErrNotFound when the incident does not exist.
+func (u *UseCase) SetCustomerFacing(ctx context.Context, incidentID, channel, actor, summary string) error {
+	if _, err := u.slackRepo.GetNewRelicIncidentByID(ctx, incidentID, channel); err != nil {
+		return err
+	}
+
+	flagged, err := u.customerFacing.IsCustomerFacing(ctx, incidentID, channel)
+	if err != nil {
+		return err
+	}
+	if err := u.customerFacing.InsertCustomerFacing(ctx, incidentID, channel, actor, strings.TrimSpace(summary)); err != nil {
+		return err
+	}
+	if !flagged {
+		u.recordEvent(ctx, IncidentEvent{
+			IncidentID: incidentID,
+			Channel:    channel,
+			Type:       EventCustomerFacing,
+			Actor:      actor,
+		})
+	}
+
+	u.publishStatusPage(ctx, incidentID, channel)
+	return nil
+}
+
+// isCustomerFacing reports whether the incident is flagged for the status page.
+func (u *UseCase) isCustomerFacing(ctx context.Context, incidentID, channel string) bool {
+	flagged, err := u.customerFacing.IsCustomerFacing(ctx, incidentID, channel)
+	if err != nil {
+		log.Errorf("Error GET customer facing flag on database: %s", err)
+		return false
+	}
+
+	return flagged
+}
+
+// publishStatusPage regenerates the status page when the incident is customer facing.
+func (u *UseCase) publishStatusPage(ctx context.Context, incidentID, channel string) {
+	if u.statusPage == nil || !u.isCustomerFacing(ctx, incidentID, channel) {
+		return
+	}
+
+	if err := u.PublishStatusPage(ctx); err != nil {
+		log.Errorf("Failed publish status page because: %s", err)
+	}
+}
+
+// BuildStatusFeed derives the status page from customer-facing incidents that
+// are still open or started within the last statusPageHistoryDays days.
+func (u *UseCase) BuildStatusFeed(ctx context.Context) (StatusFeed, error) {
+	feed := StatusFeed{
+		Status:      ComponentOperational,
+		Components:  []ComponentStatus{},
+		Incidents:   []StatusIncident{},
+		GeneratedAt: time.Now(),
+	}
+	if u.statusPage != nil {
+		feed.Title = u.statusPage.Title
+	}
+
+	candidates := map[incidentKey]entitySlack.Incident{}
+	for _, query := range []IncidentQuery{
+		{Status: []string{"open", "acknowledged"}, Limit: maxSearchLimit},
+		{From: time.Now().AddDate(0, 0, -statusPageHistoryDays), Limit: maxSearchLimit},
+	} {
+		incidents, _, err := u.SearchIncidents(ctx, query)
+		if err != nil {
+			return feed, err
+		}
+		for _, incident := range incidents {
+			candidates[incidentKey{incident.IncidentID, incident.Channel}] = incident
+		}
+	}
+
+	components := map[string]string{}
+	for _, incident := range candidates {
+		if !u.isCustomerFacing(ctx, incident.IncidentID, incident.Channel) {
+			continue
+		}
+
+		published, err := u.statusIncident(ctx, incident)
+		if err != nil {
+			return feed, err
+		}
+		feed.Incidents = append(feed.Incidents, published)
+
+		status := componentStatus(incident.Status)
+		if statusWeight(status) > statusWeight(components[published.Component]) {
+			components[published.Component] = status
+		}
+		if statusWeight(status) > statusWeight(feed.Status) {
+			feed.Status = status
+		}
+	}
+
+	for name, status := range components {
+		feed.Components = append(feed.Components, ComponentStatus{Name: name, Status: status})
+	}
+	sort.Slice(feed.Components, func(i, j int) bool {
+		return feed.Components[i].Name < feed.Components[j].Name
+	})
+	sort.Slice(feed.Incidents, func(i, j int) bool {
+		return feed.Incidents[i].StartedAt.After(feed.Incidents[j].StartedAt)
+	})
+
+	return feed, nil
+}
+
+// statusIncident is the public view of incident. Alert condition names and
+// root causes are internal, so it is named by its customer-facing summary.
+func (u *UseCase) statusIncident(ctx context.Context, incident entitySlack.Incident) (StatusIncident, error) {
+	summary, err := u.customerFacing.GetCustomerFacingSummary(ctx, incident.IncidentID, incident.Channel)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return StatusIncident{}, err
+	}
+
+	published := StatusIncident{
+		ID:        incident.IncidentID,
+		Name:      summary,
+		Component: u.GetLabels(incident.Labels, statusPageComponentLabel),
+		Status:    publicStatus(incident.Status),
+		StartedAt: incident.StartTime,
+		Updates:   []StatusUpdate{},
+	}
+	if published.Name == "" {
+		published.Name = statusPageDefaultSummary
+	}
+	if published.Component == "" {
+		published.Component = statusPageDefaultComponent
+	}
+	if incident.Status == "closed" && !incident.RecoverTime.IsZero() {
+		resolvedAt := incident.RecoverTime
+		published.ResolvedAt = &resolvedAt
+	}
+
+	timeline, err := u.GetIncidentTimeline(ctx, incident.IncidentID, incident.Channel)
+	if err != nil {
+		return published, err
+	}
+	for _, event := range timeline {
+		if event.Type != EventStateChanged {
+			continue
+		}
+		published.Updates = append(published.Updates, StatusUpdate{
+			Status: publicStatus(event.State),
+			Body:   publicUpdate(event.State),
+			At:     event.CreatedAt,
+		})
+	}
+	sort.SliceStable(published.Updates, func(i, j int) bool {
+		return published.Updates[i].At.After(published.Updates[j].At)
+	})
+
+	return published, nil
+}
+
+// PublishStatusPage writes status.json and index.html. Files are replaced
+// atomically so the web server never serves a partial page.
+func (u *UseCase) PublishStatusPage(ctx context.Context) error {
+	feed, err := u.BuildStatusFeed(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(u.statusPage.Dir, 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(u.statusPage.Dir, "status.json"), b); err != nil {
+		return err
+	}
+
+	var html bytes.Buffer
+	if err := statusPageTemplate.Execute(&html, feed); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(u.statusPage.Dir, "index.html"), html.Bytes())
+}
+
+func componentStatus(state string) string {
+	switch state {
+	case "open":
+		return ComponentMajorOutage
+	case "acknowledged":
+		return ComponentDegraded
+	}
+
+	return ComponentOperational
+}
+
+func statusWeight(status string) int {
+	switch status {
+	case ComponentMajorOutage:
+		return 3
+	case ComponentDegraded:
+		return 2
+	case ComponentOperational:
+		return 1
+	}
+
+	return 0
+}
+
+func publicStatus(state string) string {
+	switch state {
+	case "open":
+		return "investigating"
+	case "acknowledged":
+		return "identified"
+	case "closed":
+		return "resolved"
+	}
+
+	return state
+}
+
+func publicUpdate(state string) string {
+	switch state {
+	case "open":
+		return "We are investigating reports of a service disruption."
+	case "acknowledged":
+		return "The issue has been identified and a fix is being worked on."
+	case "closed":
+		return "This incident has been resolved."
+	}
+
+	return ""
+}
+
+// writeFileAtomic replaces path with b through a uniquely named temporary
+// file in the same directory, so concurrent writers never share one.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"when": func(t time.Time) string { return t.Format("Jan 2, 15:04 MST") },
+	"label": func(s string) string {
+		s = strings.Replace(s, "_", " ", -1)
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="60">
+<title>{{.Title}} Status</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 760px; margin: 2em auto; color: #1d1c1d; }
+  .banner { padding: 1em; border-radius: 6px; color: #fff; font-weight: bold; }
+  .operational { background: #00a06b; } .degraded_performance { background: #c77700; } .major_outage { background: #d00000; }
+  ul.components { list-style: none; padding: 0; }
+  ul.components li { display: flex; justify-content: space-between; padding: .6em 0; border-bottom: 1px solid #eee; }
+  .incident { margin-top: 1.5em; } .update { margin: .4em 0 .4em 1em; } .muted { color: #888; font-size: .85em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} Status</h1>
+<div class="banner {{.Status}}">{{if eq .Status "operational"}}All Systems Operational{{else}}{{label .Status}}{{end}}</div>
+
+<ul class="components">
+{{range .Components}}  <li><span>{{.Name}}</span><span>{{label .Status}}</span></li>
+{{end}}</ul>
+
+<h2>Incidents</h2>
+{{range .Incidents}}<div class="incident">
+  <strong>{{.Name}}</strong> <span class="muted">{{.Component}} &middot; {{label .Status}}</span>
+  {{range .Updates}}<div class="update"><strong>{{label .Status}}</strong> - {{.Body}} <span class="muted">{{when .At}}</span></div>
+  {{end}}</div>
+{{else}}<p class="muted">No incidents reported recently.</p>
+{{end}}
+<p class="muted">Last updated {{when .GeneratedAt}}</p>
+</body>
+</html>
+`))
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAckFormCustomerFacingCheckbox(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"without status page", nil, false},
+		{"with status page", []Option{WithStatusPage(StatusPage{Dir: t.TempDir()})}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, repo := newTestUseCase(t, tc.opts...)
+			opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+			u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01"))
+
+			calls := repo.Calls("SubmitButtonAction")
+			if len(calls) != 1 {
+				t.Fatalf("SubmitButtonAction calls = %+v", calls)
+			}
+			if got := blockIDs(calls[0].Blocks)[CustomerFacingBlock]; got != tc.want {
+				t.Errorf("Ack form has %s input = %v, want %v", CustomerFacingBlock, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCustomerFacingIsPersisted(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCustomerFacing()
+	u, repo := newTestUseCase(t, WithCustomerFacingStore(store))
+	u.ProcessIncident(ctx, testPayload("42", "open"))
+
+	if err := u.SetCustomerFacing(ctx, "42", "C1", "U0ALICE01", ""); err != nil {
+		t.Fatalf("SetCustomerFacing: %v", err)
+	}
+	if err := u.SetCustomerFacing(ctx, "42", "C1", "U0BOB0001", ""); err != nil {
+		t.Fatalf("SetCustomerFacing again: %v", err)
+	}
+
+	events := 0
+	timeline, _ := u.GetIncidentTimeline(ctx, "42", "C1")
+	for _, event := range timeline {
+		if event.Type == EventCustomerFacing {
+			events++
+		}
+	}
+	if events != 1 {
+		t.Errorf("%d customer facing events, want 1", events)
+	}
+
+	// The flag outlives the event log of the process that set it
+	restarted := New(repo, WithConfig(u.config), WithCustomerFacingStore(store))
+	if !restarted.isCustomerFacing(ctx, "42", "C1") {
+		t.Error("customer facing flag lost on restart")
+	}
+	if restarted.isCustomerFacing(ctx, "42", "C2") {
+		t.Error("flag in C1 shows up in C2")
+	}
+}
+
+func TestSetCustomerFacingUnknownIncident(t *testing.T) {
+	u, _ := newTestUseCase(t, WithAuthenticator(func(r *http.Request) (string, error) {
+		if r.Header.Get("Authorization") != "Bearer alice" {
+			return "", errors.New("unknown token")
+		}
+		return "U0ALICE01", nil
+	}))
+	u.ProcessIncident(context.Background(), testPayload("42", "open"))
+
+	if err := u.SetCustomerFacing(context.Background(), "404", "C1", "U0ALICE01", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetCustomerFacing(unknown) = %v, want ErrNotFound", err)
+	}
+	if u.isCustomerFacing(context.Background(), "404", "C1") {
+		t.Error("unknown incident was flagged")
+	}
+
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/42/customer-facing?channel=C1&actor=U0MALLORY", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST without token = %d, want 401", rec.Code)
+	}
+
+	for target, want := range map[string]int{
+		"/incidents/42/customer-facing":             http.StatusBadRequest,
+		"/incidents/404/customer-facing?channel=C1": http.StatusNotFound,
+		"/incidents/42/customer-facing?channel=C2":  http.StatusNotFound,
+		"/incidents/42/customer-facing?channel=C1":  http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"summary": "Checkout is slow"}`))
+		req.Header.Set("Authorization", "Bearer alice")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("POST %s = %d, want %d", target, rec.Code, want)
+		}
+	}
+
+	timeline, _ := u.GetIncidentTimeline(context.Background(), "42", "C1")
+	for _, event := range timeline {
+		if event.Type == EventCustomerFacing && event.Actor != "U0ALICE01" {
+			t.Errorf("customer facing actor = %q, want the authenticated user", event.Actor)
+		}
+	}
+}
+
+func TestStatusPagePublishesOnlyTheSummary(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	u, _ := newTestUseCase(t, WithStatusPage(StatusPage{Dir: dir, Title: "Acme"}))
+	u.ProcessIncident(ctx, testPayload("42", "open"))
+	u.ProcessIncident(ctx, testPayload("43", "open"))
+	u.recordEvent(ctx, IncidentEvent{IncidentID: "42", Channel: "C1", Type: EventRootCause, Detail: "bad-deploy"})
+
+	if err := u.SetCustomerFacing(ctx, "42", "C1", "U0ALICE01", "Checkout is slow for some customers"); err != nil {
+		t.Fatalf("SetCustomerFacing(42): %v", err)
+	}
+	if err := u.SetCustomerFacing(ctx, "43", "C1", "U0ALICE01", ""); err != nil {
+		t.Fatalf("SetCustomerFacing(43): %v", err)
+	}
+
+	feed, err := u.BuildStatusFeed(ctx)
+	if err != nil || len(feed.Incidents) != 2 {
+		t.Fatalf("BuildStatusFeed = %+v, %v", feed, err)
+	}
+	names := map[string]string{}
+	for _, incident := range feed.Incidents {
+		names[incident.ID] = incident.Name
+		if incident.Component != statusPageDefaultComponent {
+			t.Errorf("incident %s component = %q, want %q", incident.ID, incident.Component, statusPageDefaultComponent)
+		}
+	}
+	if names["42"] != "Checkout is slow for some customers" || names["43"] != statusPageDefaultSummary {
+		t.Errorf("published names = %v", names)
+	}
+
+	for _, file := range []string{"status.json", "index.html"} {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		for _, internal := range []string{"CPU high", "bad deploy", "bad-deploy"} {
+			if strings.Contains(string(b), internal) {
+				t.Errorf("%s publishes internal %q", file, internal)
+			}
+		}
+	}
+
+	// Temporary files are renamed into place or removed
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("status page files = %v, want only status.json and index.html", entries)
+	}
+}
@@ -10,32 +10,145 @@ import (
 
 	"github.com/slack-go/slack"
 	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
-	"github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/pkg/webhook"
 	"github.com/tokopedia/tdk/go/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type UseCase struct {
-	slackRepo slackRepository
+	slackRepo      slackRepository
+	baseRepo       slackRepository // undecorated slackRepo, for optional capability checks
+	notifiers      NotificationRouter
+	events         eventRepository
+	actionItems    actionItemRepository
+	config         *ConfigStore
+	broker         *eventBroker
+	metrics        *Metrics
+	tracer         trace.Tracer
+	links          linkRepository
+	users          *userCache
+	statusPage     *StatusPage
+	tickets        TicketSystem
+	ticketStore    ticketRepository
+	assignments    assignmentRepository
+	customerFacing customerFacingRepository
+	coalescer      *coalescingRepository
+	authenticator  Authenticator
+	// assignLocks serializes assignments of an incident
+	assignLocks keyedMutex
+	// incidentLocks serializes the events of an incident with its deferred
+	// summary
+	incidentLocks keyedMutex
+	// diagnostics holds a slot per incident running its diagnostics
+	diagnostics chan struct{}
+	// ticketSeverity is the lowest severity that opens a ticket
+	ticketSeverity Severity
+	// coalesceWindow batches Slack message updates when positive
+	coalesceWindow time.Duration
 }
 
-func New(slack slackRepository) *UseCase {
-	return &UseCase{
-		slackRepo: slack,
+func New(slack slackRepository, opts ...Option) *UseCase {
+	u := &UseCase{
+		slackRepo:      slack,
+		baseRepo:       slack,
+		events:         NewMemoryEventLog(),
+		actionItems:    NewMemoryActionItems(),
+		broker:         newEventBroker(),
+		links:          NewMemoryLinks(),
+		users:          newUserCache(),
+		ticketStore:    NewMemoryTickets(),
+		assignments:    NewMemoryAssignments(),
+		customerFacing: NewMemoryCustomerFacing(),
+		diagnostics:    make(chan struct{}, defaultDiagnosticsConcurrency),
 	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if u.config == nil {
+		// Fall back to the package-level webhook config, keeping it even when invalid
+		cfg := ConfigFromWebhook()
+		if err := cfg.Validate(); err != nil {
+			log.Errorf("Invalid webhook config: %s", err)
+		}
+		u.config = &ConfigStore{}
+		u.config.current.Store(&cfg)
+	}
+	if u.metrics != nil {
+		u.metrics.register(u)
+		u.slackRepo = &instrumentedRepository{next: u.slackRepo, metrics: u.metrics}
+	}
+	if u.tracer == nil {
+		u.tracer = otel.Tracer(tracerName)
+	}
+	u.slackRepo = &tracedRepository{next: u.slackRepo, tracer: u.tracer}
+	// Outermost, so metrics and traces cover the sends actually made to Slack
+	if u.coalesceWindow > 0 {
+		u.coalescer = newCoalescingRepository(u.slackRepo, u.coalesceWindow)
+		u.slackRepo = u.coalescer
+	}
+
+	return u
 }
 
 func (u *UseCase) ProcessIncident(ctx context.Context, data entitySlack.NewRelicReplyThread) (entitySlack.Incident, error) {
+	ctx, span := u.startSpan(ctx, "UseCase.ProcessIncident", data.GetIncidentID(), data.GetChannel())
+	span.SetAttributes(attrState.String(data.GetState()))
+	defer span.End()
+
+	unlock := u.incidentLocks.lock(data.GetIncidentID(), data.GetChannel())
+	defer unlock()
+
 	// Get NewRelic Incident BY incident ID
 	incident, _ := u.slackRepo.GetNewRelicIncident(ctx, data.GetIncidentID(), data.GetChannel())
 	// if err != nil {
 	// 	log.Info("Incident on database not found: %s", err)
 	// }
 
+	u.metrics.observeIncident(data)
+
+	// Keep out-of-order deliveries in history without regressing the current state
+	stale := isStale(incident.Status, data.GetState())
+	u.recordReceived(ctx, data, time.Now(), stale)
+	if stale {
+		log.Infof("Ignore stale %s event for incident %s, current status is %s", data.GetState(), data.GetIncidentID(), incident.Status)
+		return incident, nil
+	}
+
+	// A raised severity is an escalation, and may end a deferral
+	u.escalate(ctx, data, incident)
+
+	// Low-severity incidents outside business hours wait for the next summary
+	if u.deferIncident(ctx, data, incident) {
+		return u.slackRepo.GetNewRelicIncidentByID(ctx, data.GetIncidentID(), data.GetChannel())
+	}
+
 	incidentTs := incident.MessageTimestamp
 	if incidentTs == "" {
-		// Store Incident
-		if err := u.RegisterIncident(ctx, data); err != nil {
-			log.Errorf("Failed send incident request: %v", err)
+		// Store Incident, or bring one stored without a message up to date
+		if incident.IncidentID == "" {
+			if err := u.RegisterIncident(ctx, data); err != nil {
+				log.Errorf("Failed send incident request: %v", err)
+			}
+		} else if incident.Status != data.GetState() {
+			var recoverTime time.Time
+			if data.GetState() == "closed" || data.GetState() == "acknowledged" {
+				recoverTime = time.Now().Local()
+			}
+
+			if err := u.slackRepo.UpdateNewRelicIncidentStatusByID(ctx, data.GetState(), "", data.GetChannel(), data.GetIncidentID(), recoverTime); err != nil {
+				log.Errorf("Error store message to database: %s", err)
+			}
+		}
+
+		if incident.Status != data.GetState() {
+			u.recordEvent(ctx, IncidentEvent{
+				IncidentID:    data.GetIncidentID(),
+				Channel:       data.GetChannel(),
+				Type:          EventStateChanged,
+				State:         data.GetState(),
+				PreviousState: incident.Status,
+				Actor:         data.GetVendor(),
+			})
 		}
 
 		// Get NewRelic Incident BY incident ID
@@ -45,17 +158,20 @@ func (u *UseCase) ProcessIncident(ctx context.Context, data entitySlack.NewRelic
 		}
 
 		// Send Slack Message
-		_, ts, err := u.slackRepo.SendMessage(ctx, data.GetChannel(), u.GetMessageSummary(data, i.StartTime, i.RecoverTime), u.GetColor(data), incidentTs, data.GetVendor(), data.GetURL())
+		_, ts, err := u.slackRepo.SendMessage(ctx, data.GetChannel(), withSeverity(u.GetMessageSummary(data, i.StartTime, i.RecoverTime), i.Severity), u.GetColor(data), incidentTs, data.GetVendor(), data.GetURL())
 		if err != nil {
 			log.Errorf("Failed send slack message to channel %s because: %s", data.GetChannel(), err)
 		}
 
-		// Store Slack Message
+		// Store Slack Message, leaving the incident without one when nothing was
+		// posted so the next event posts it again
 		triggerID := ""
 		workspace := ""
 		userACK := ""
-		if err := u.slackRepo.InsertMessage(ctx, triggerID, workspace, userACK, ts, data.GetIncidentID()); err != nil {
-			log.Errorf("Error store message to database: %s", err)
+		if err == nil && ts != "" {
+			if err := u.insertMessage(ctx, triggerID, workspace, userACK, ts, data.GetIncidentID(), data.GetChannel()); err != nil {
+				log.Errorf("Error store message to database: %s", err)
+			}
 		}
 	} else {
 		var recoverTime time.Time
@@ -70,6 +186,17 @@ func (u *UseCase) ProcessIncident(ctx context.Context, data entitySlack.NewRelic
 			log.Errorf("Error store message to database: %s", err)
 		}
 
+		if incident.Status != data.GetState() {
+			u.recordEvent(ctx, IncidentEvent{
+				IncidentID:    data.GetIncidentID(),
+				Channel:       data.GetChannel(),
+				Type:          EventStateChanged,
+				State:         data.GetState(),
+				PreviousState: incident.Status,
+				Actor:         data.GetVendor(),
+			})
+		}
+
 		// Get NewRelic Incident BY incident ID
 		i, err := u.slackRepo.GetNewRelicIncidentByID(ctx, data.GetIncidentID(), data.GetChannel())
 		if err != nil {
@@ -77,10 +204,16 @@ func (u *UseCase) ProcessIncident(ctx context.Context, data entitySlack.NewRelic
 		}
 
 		// Update Slack Message
-		_, _, err = u.slackRepo.UpdateMessage(ctx, data.GetChannel(), u.GetMessageSummary(data, i.StartTime, i.RecoverTime), u.GetColor(data), incidentTs, data.GetVendor(), data.GetURL())
+		summary := withAssignee(withSeverity(u.GetMessageSummary(data, i.StartTime, i.RecoverTime), i.Severity), u.currentAssignee(ctx, data.GetIncidentID(), data.GetChannel()))
+		_, _, err = u.slackRepo.UpdateMessage(ctx, data.GetChannel(), summary, u.GetColor(data), incidentTs, data.GetVendor(), data.GetURL())
 		if err != nil {
 			log.Errorf("Failed send slack message to channel %s because: %s", data.GetChannel(), err)
 		}
+
+		// Resolve linked child incidents together with their parent
+		if data.GetState() == "closed" && incident.Status != "closed" {
+			u.resolveChildren(ctx, i)
+		}
 	}
 
 	// Get NewRelic Incident BY incident ID
@@ -95,6 +228,22 @@ func (u *UseCase) ProcessIncident(ctx context.Context, data entitySlack.NewRelic
 		log.Errorf("Failed send slack message to channel %s because: %s", data.GetChannel(), err)
 	}
 
+	// Send the same update to email and webhook notifiers routed to this channel
+	u.notify(ctx, data, incidentMetadata)
+	u.publishStatusPage(ctx, data.GetIncidentID(), data.GetChannel())
+
+	// Open a ticket for new high-severity incidents and resolve it on close
+	if incidentTs == "" {
+		u.openTicket(ctx, incidentMetadata)
+
+		// Offer ownership and runbooks, and run the condition's diagnostics
+		condition, _ := u.config.Load().Catalog().ByID(data.GetConditionID())
+		u.postIncidentActions(ctx, condition, incidentMetadata)
+		u.startDiagnostics(ctx, condition, incidentMetadata)
+	} else if data.GetState() == "closed" {
+		u.resolveTicket(ctx, incidentMetadata)
+	}
+
 	return incidentMetadata, nil
 }
 
@@ -108,7 +257,7 @@ func (u *UseCase) RegisterIncident(ctx context.Context, data entitySlack.NewReli
 	incidentOwner := data.GetOwner()
 	incidentGeneratedBy := data.GetVendor()
 	incidentStatus := data.GetState()
-	incidentSeverity := data.GetSeverity()
+	incidentSeverity := string(u.severityOf(data))
 	incidentConditionID := data.GetConditionID()
 	incidentLabels := data.GetLabels()
 	incidentRootCause := "null"
@@ -147,11 +296,15 @@ func (u *UseCase) AckMessage(ctx context.Context, message slack.InteractionCallb
 	tsMessage := message.Message.Timestamp
 	channelID := message.Container.ChannelID
 	triggerID := message.TriggerID
-	username := message.User.Name
+	actor := actorOf(message.User)
 	workspace := message.Team.Domain
 
+	ctx, span := u.startSpan(ctx, "UseCase.AckMessage", "", channelID)
+	span.SetAttributes(attrMessageTs.String(tsMessage))
+	defer span.End()
+
 	// Record Slack Message related metadata.
-	if err := u.slackRepo.UpdateMessageByTimestamp(ctx, triggerID, workspace, username, tsMessage, channelID); err != nil {
+	if err := u.slackRepo.UpdateMessageByTimestamp(ctx, triggerID, workspace, actor, tsMessage, channelID); err != nil {
 		log.Errorf("Error store message to database: %s", err)
 	}
 
@@ -166,31 +319,82 @@ func (u *UseCase) AckMessage(ctx context.Context, message slack.InteractionCallb
 	if err != nil {
 		log.Errorf("Error GET incident on database: %s", err)
 	}
+	span.SetAttributes(attrIncidentID.String(slackMessage.IncidentID))
+
+	u.acknowledge(ctx, incident, actor)
 
 	// Construct Ack form
 	blockActions := message.ActionCallback.BlockActions
 	optionsData := u.GetOptionStr(incident.ConditionID)
 	incidentTitle := u.GetTitle(incident.GeneratedBy, incident.Status, incident.Name, incident.URL)
-	incidentColor := u.GetColorStr(incident.Status)
-	incidentMessage := u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime)
+	incidentColor := u.GetColorStr(incident.Status, incident.Severity)
+	incidentMessage := withAssignee(withSeverity(u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime), incident.Severity), u.currentAssignee(ctx, incident.IncidentID, incident.Channel))
 
 	// Respond with Ack form
-	result, err := u.slackRepo.SubmitButtonAction(blockActions, optionsData, channelID, tsMessage, triggerID, incidentTitle, incidentMessage, incidentColor, username, incident.URL, replaceOriginalMessage)
+	_, submitSpan := u.startSpan(ctx, "slackRepository.SubmitButtonAction", slackMessage.IncidentID, channelID)
+	var result string
+	if forms, ok := u.baseRepo.(ackFormRepository); ok {
+		// Form submissions bypass the decorators, so send coalesced replies first
+		u.sendNow(channelID, tsMessage)
+		result, err = forms.SubmitButtonActionWithForm(blockActions, optionsData, channelID, tsMessage, triggerID, incidentTitle, incidentMessage, incidentColor, mention(actor), incident.URL, replaceOriginalMessage, u.ackFormBlocks()...)
+	} else {
+		result, err = u.slackRepo.SubmitButtonAction(blockActions, optionsData, channelID, tsMessage, triggerID, incidentTitle, incidentMessage, incidentColor, mention(actor), incident.URL, replaceOriginalMessage)
+	}
 	if err != nil {
 		log.Errorf("Failed update slack block message because: %s", err)
 	}
+	endSpan(submitSpan, err)
 
 	return incident, result, slackMessage.MessageTimestamp, nil
 }
 
+// acknowledge records that actor acknowledged incident: the ack latency of
+// the first ack, the acknowledged event and, for unassigned incidents, the
+// assignment to actor. Later acks keep the assignee.
+func (u *UseCase) acknowledge(ctx context.Context, incident entitySlack.Incident, actor string) {
+	if incident.IncidentID == "" {
+		return
+	}
+
+	if !u.hasEvent(ctx, incident.IncidentID, incident.Channel, EventAcknowledged) {
+		u.metrics.observeAck(incident.StartTime)
+	}
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: incident.IncidentID,
+		Channel:    incident.Channel,
+		Type:       EventAcknowledged,
+		State:      incident.Status,
+		Actor:      actor,
+	})
+
+	u.firstAckAssignment(ctx, incident, actor)
+}
+
+// ackFormBlocks are the optional inputs rendered in the Ack form below the root cause.
+func (u *UseCase) ackFormBlocks() []slack.Block {
+	blocks := ActionItemBlocks()
+	blocks = append(blocks, LinkBlocks()...)
+	if u.statusPage != nil {
+		blocks = append(blocks, CustomerFacingBlocks()...)
+	}
+	return blocks
+}
+
 // SubmitAckForm accepts Ack form submission and processes it (e.g. updates the Slack Message with new information).
 func (u *UseCase) SubmitAckForm(ctx context.Context, message slack.InteractionCallback, messageTimestamp, channel string) (entitySlack.Incident, string, error) {
 	var actionValue string
 	replaceOriginalMessage := true
-	username := message.User.Name
+	actor := actorOf(message.User)
 	viewState := message.View.State.Values
 
-	for _, state := range viewState {
+	ctx, span := u.startSpan(ctx, "UseCase.SubmitAckForm", "", channel)
+	span.SetAttributes(attrMessageTs.String(messageTimestamp))
+	defer span.End()
+
+	for blockID, state := range viewState {
+		if isActionItemBlock(blockID) || isLinkBlock(blockID) || blockID == CustomerFacingBlock {
+			continue
+		}
 		for _, value := range state {
 			selectedOptions := value.SelectedOption.Value
 			textValue := value.Value
@@ -209,26 +413,73 @@ func (u *UseCase) SubmitAckForm(ctx context.Context, message slack.InteractionCa
 	if err != nil {
 		log.Errorf("Error GET message on database: %s", err)
 	}
+	span.SetAttributes(attrIncidentID.String(slackMessage.IncidentID))
 
 	// Store Incident information from Ack form
 	if err := u.slackRepo.UpdateNewRelicIncidentByID(ctx, actionValue, slackMessage.IncidentID); err != nil {
 		log.Errorf("Error store message to database: %s", err)
 	}
 
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: slackMessage.IncidentID,
+		Channel:    channel,
+		Type:       EventRootCause,
+		Actor:      actor,
+		Detail:     actionValue,
+	})
+
 	// Retrieve Incident information.
 	incident, err := u.slackRepo.GetNewRelicIncidentByMsgTimestamp(ctx, slackMessage.IncidentID, slackMessage.MessageTimestamp)
 	if err != nil {
 		log.Errorf("Error GET incident on database: %s", err)
 	}
 
+	// Attach follow-up action item, if one was filled in the Ack form.
+	if item, ok := actionItemFromForm(viewState); ok {
+		item.IncidentID = slackMessage.IncidentID
+		item.Channel = channel
+		if _, err := u.CreateActionItem(ctx, item); err != nil {
+			log.Errorf("Error store action item to database: %s", err)
+		}
+	}
+
+	// Link to a parent incident, if one was filled in the Ack form.
+	if link, ok := linkFromForm(viewState); ok {
+		link.IncidentID = slackMessage.IncidentID
+		link.Channel = channel
+		link.CreatedBy = actor
+		if _, err := u.LinkIncidents(ctx, link); err != nil {
+			log.Errorf("Failed link incident %s because: %s", slackMessage.IncidentID, err)
+		}
+	}
+
+	// Publish on the status page, if flagged as customer facing in the Ack form.
+	customerFacing := false
+	for _, value := range viewState[CustomerFacingBlock] {
+		customerFacing = customerFacing || len(value.SelectedOptions) > 0
+	}
+	summary := ""
+	for _, value := range viewState[CustomerFacingSummaryBlock] {
+		summary = value.Value
+	}
+	if customerFacing {
+		if err := u.SetCustomerFacing(ctx, slackMessage.IncidentID, channel, actor, summary); err != nil {
+			log.Errorf("Failed flag incident %s as customer facing because: %s", slackMessage.IncidentID, err)
+		}
+	} else {
+		u.publishStatusPage(ctx, slackMessage.IncidentID, channel)
+	}
+
 	// Update Slack Message to reflect new information from Ack form.
 	incidentTitle := u.GetTitle(incident.GeneratedBy, incident.Status, incident.Name, incident.URL)
-	incidentColor := u.GetColorStr(incident.Status)
-	incidentMessage := u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime)
-	_, err = u.slackRepo.ReplaceMessage(incident.Channel, slackMessage.MessageTimestamp, actionValue, incidentTitle, incidentMessage, incidentColor, username, incident.URL, replaceOriginalMessage)
+	incidentColor := u.GetColorStr(incident.Status, incident.Severity)
+	incidentMessage := withAssignee(withSeverity(u.GetMessageString(incident.Description, incident.Status, incident.StartTime, incident.RecoverTime), incident.Severity), u.currentAssignee(ctx, incident.IncidentID, incident.Channel))
+	_, replaceSpan := u.startSpan(ctx, "slackRepository.ReplaceMessage", slackMessage.IncidentID, incident.Channel)
+	_, err = u.slackRepo.ReplaceMessage(incident.Channel, slackMessage.MessageTimestamp, actionValue, incidentTitle, incidentMessage, incidentColor, mention(actor), incident.URL, replaceOriginalMessage)
 	if err != nil {
 		log.Errorf("Failed update slack block message because: %s", err)
 	}
+	endSpan(replaceSpan, err)
 
 	return incident, actionValue, nil
 }
@@ -236,12 +487,10 @@ func (u *UseCase) SubmitAckForm(ctx context.Context, message slack.InteractionCa
 func (u *UseCase) GetOptionStr(data int) []string {
 	optionData := []string{}
 
-	for _, v := range webhook.DiaryWebhookConfig.Slack.NewRelic {
-		if data == v.AlertConditionID {
-			for _, cause := range v.AlertConditionCause {
-				entry := cause
-				optionData = append(optionData, u.GetDataOptions(entry))
-			}
+	if v, ok := u.config.Load().Catalog().ByID(data); ok {
+		for _, cause := range v.Causes {
+			entry := cause
+			optionData = append(optionData, u.GetDataOptions(entry))
 		}
 	}
 
@@ -339,33 +588,21 @@ func (u *UseCase) GetMessageSummary(data entitySlack.NewRelicReplyThread, start,
 }
 
 func (u *UseCase) GetColor(data entitySlack.NewRelicReplyThread) string {
-	color := "FFFFFF"
-	if data.GetState() == "open" {
-		color = "FF0000"
-	} else {
-		color = "00BF85"
-	}
-
-	return color
+	return u.GetColorStr(data.GetState(), string(u.severityOf(data)))
 }
 
-func (u *UseCase) GetColorStr(status string) string {
-	color := "FFFFFF"
-	if status == "open" {
-		color = "FF0000"
-	} else {
-		color = "00BF85"
+func (u *UseCase) GetColorStr(status, severity string) string {
+	if status == "closed" {
+		return resolvedColor
 	}
 
-	return color
+	return NormalizeSeverity(severity).Color()
 }
 
 func (u *UseCase) GetIncidentName(data entitySlack.NewRelicReplyThread) string {
 	var incidentName string
-	for _, v := range webhook.DiaryWebhookConfig.Slack.NewRelic {
-		if data.GetConditionID() == v.AlertConditionID {
-			incidentName = v.AlertConditionName
-		}
+	if v, ok := u.config.Load().Catalog().ByID(data.GetConditionID()); ok {
+		incidentName = v.Name
 	}
 	return incidentName
 }
@@ -0,0 +1,331 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+var errSlackDown = errors.New("slack is down")
+
+func newTestUseCase(t *testing.T, opts ...Option) (*UseCase, *MemoryRepository) {
+	t.Helper()
+
+	cfg := Config{}
+	cfg.Slack.NewRelic = []AlertCondition{{ID: 7, Name: "CPU high", Causes: []string{"noisy-neighbour", "bad-deploy"}}}
+	store, err := NewConfigStore(cfg)
+	if err != nil {
+		t.Fatalf("NewConfigStore: %v", err)
+	}
+
+	repo := NewMemoryRepository()
+	return New(repo, append([]Option{WithConfig(store)}, opts...)...), repo
+}
+
+func testPayload(incidentID, state string) entitySlack.NewRelicReplyThread {
+	return entitySlack.NewRelicReplyThread{
+		IncidentID:  incidentID,
+		Channel:     "C1",
+		State:       state,
+		Vendor:      "newrelic",
+		Title:       "CPU high on web-1",
+		Body:        "cpu > 90%",
+		ConditionID: 7,
+		Severity:    "critical",
+	}
+}
+
+func ackCallback(channel, messageTimestamp, userID string) slack.InteractionCallback {
+	callback := slack.InteractionCallback{TriggerID: "trigger-1"}
+	callback.Message.Timestamp = messageTimestamp
+	callback.Container.ChannelID = channel
+	callback.User.ID = userID
+	callback.Team.Domain = "acme"
+	return callback
+}
+
+// blockIDs returns the IDs of the input blocks among blocks.
+func blockIDs(blocks []slack.Block) map[string]bool {
+	ids := map[string]bool{}
+	for _, block := range blocks {
+		if input, ok := block.(*slack.InputBlock); ok {
+			ids[input.BlockID] = true
+		}
+	}
+	return ids
+}
+
+func TestProcessIncident(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, err := u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil {
+		t.Fatalf("ProcessIncident(open): %v", err)
+	}
+	if opened.Status != "open" || opened.MessageTimestamp == "" || opened.Severity != string(SEV1) {
+		t.Fatalf("opened incident = %+v", opened)
+	}
+
+	sent := repo.Calls("SendMessage")
+	if len(sent) != 1 || sent[0].Channel != "C1" || sent[0].Color != SEV1.Color() || !strings.Contains(sent[0].Text, "cpu > 90%") {
+		t.Fatalf("SendMessage calls = %+v", sent)
+	}
+	replies := repo.Calls("ReplyMessageInThread")
+	if len(replies) != 1 || replies[0].ThreadTimestamp != opened.MessageTimestamp {
+		t.Fatalf("ReplyMessageInThread calls = %+v", replies)
+	}
+
+	closed, err := u.ProcessIncident(ctx, testPayload("42", "closed"))
+	if err != nil {
+		t.Fatalf("ProcessIncident(closed): %v", err)
+	}
+	if closed.Status != "closed" || closed.RecoverTime.IsZero() || closed.MessageTimestamp != opened.MessageTimestamp {
+		t.Fatalf("closed incident = %+v", closed)
+	}
+
+	updates := repo.Calls("UpdateMessage")
+	if len(updates) != 1 || updates[0].Timestamp != opened.MessageTimestamp || updates[0].Color != resolvedColor {
+		t.Fatalf("UpdateMessage calls = %+v", updates)
+	}
+	if n := len(repo.Calls("SendMessage")); n != 1 {
+		t.Errorf("SendMessage called %d times, want once per incident", n)
+	}
+	if n := len(repo.Calls("ReplyMessageInThread")); n != 2 {
+		t.Errorf("ReplyMessageInThread called %d times, want once per event", n)
+	}
+}
+
+func TestProcessIncidentDuplicate(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	first, err := u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	second, err := u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil {
+		t.Fatalf("ProcessIncident(duplicate): %v", err)
+	}
+
+	if sent := repo.Calls("SendMessage"); len(sent) != 1 {
+		t.Errorf("SendMessage calls = %+v, want one parent post", sent)
+	}
+	if incidents := repo.Incidents(); len(incidents) != 1 {
+		t.Errorf("incidents = %+v, want one row", incidents)
+	}
+	if second.MessageTimestamp != first.MessageTimestamp {
+		t.Errorf("duplicate moved the parent message from %q to %q", first.MessageTimestamp, second.MessageTimestamp)
+	}
+}
+
+func TestProcessIncidentSendFailure(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	repo.Fail("SendMessage", errSlackDown)
+	incident, err := u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	if incident.Status != "open" || incident.MessageTimestamp != "" {
+		t.Fatalf("incident after failed send = %+v, want stored without a message", incident)
+	}
+	if calls := repo.Calls("SendMessage"); len(calls) != 0 {
+		t.Fatalf("failed SendMessage recorded: %+v", calls)
+	}
+
+	// The next event posts the message that could not be sent
+	repo.Fail("SendMessage", nil)
+	incident, err = u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	sent := repo.Calls("SendMessage")
+	if len(sent) != 1 || incident.MessageTimestamp == "" {
+		t.Fatalf("retry: incident = %+v, SendMessage calls = %+v", incident, sent)
+	}
+}
+
+func TestProcessIncidentUpdateFailure(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+
+	repo.Fail("UpdateMessage", errSlackDown)
+	closed, err := u.ProcessIncident(ctx, testPayload("42", "closed"))
+	if err != nil {
+		t.Fatalf("ProcessIncident: %v", err)
+	}
+	if closed.Status != "closed" {
+		t.Errorf("status = %s, want closed even though Slack failed", closed.Status)
+	}
+
+	// The thread reply is still attempted after the parent update failed
+	replies := repo.Calls("ReplyMessageInThread")
+	if len(replies) != 2 || replies[1].ThreadTimestamp != opened.MessageTimestamp || !strings.Contains(replies[1].Text, "closed") {
+		t.Errorf("ReplyMessageInThread calls = %+v", replies)
+	}
+}
+
+func TestAckMessage(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+
+	incident, result, ts, err := u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01"))
+	if err != nil {
+		t.Fatalf("AckMessage: %v", err)
+	}
+	if incident.IncidentID != "42" || ts != opened.MessageTimestamp || result != "ok" {
+		t.Fatalf("AckMessage = %+v, %q, %q", incident, result, ts)
+	}
+
+	calls := repo.Calls("SubmitButtonAction")
+	if len(calls) != 1 {
+		t.Fatalf("SubmitButtonAction calls = %+v", calls)
+	}
+	call := calls[0]
+	if call.Channel != "C1" || call.Timestamp != opened.MessageTimestamp || call.Username != "<@U0ALICE01>" {
+		t.Errorf("SubmitButtonAction call = %+v", call)
+	}
+	if len(call.Options) != 2 || call.Options[0] != "noisy neighbour" || call.Options[1] != "bad deploy" {
+		t.Errorf("root cause options = %q", call.Options)
+	}
+	formBlocks := blockIDs(call.Blocks)
+	for _, blockID := range []string{ActionItemDescriptionBlock, ActionItemOwnerBlock, ActionItemDueDateBlock, LinkTypeBlock, LinkParentBlock} {
+		if !formBlocks[blockID] {
+			t.Errorf("Ack form has no %s input, blocks = %v", blockID, formBlocks)
+		}
+	}
+
+	message, err := repo.GetMessageByTimestamp(ctx, opened.MessageTimestamp, "C1")
+	if err != nil || message.UserACK != "U0ALICE01" || message.TriggerID != "trigger-1" || message.Workspace != "acme" {
+		t.Errorf("stored message = %+v, %v", message, err)
+	}
+	if !u.hasEvent(ctx, "42", "C1", EventAcknowledged) {
+		t.Error("no acknowledged event recorded")
+	}
+}
+
+// baseOnly hides the optional capabilities of a repository.
+type baseOnly struct {
+	slackRepository
+}
+
+func TestAckMessageWithBaseRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	u := New(baseOnly{repo})
+
+	opened, err := u.ProcessIncident(ctx, testPayload("42", "open"))
+	if err != nil || opened.MessageTimestamp == "" {
+		t.Fatalf("ProcessIncident = %+v, %v", opened, err)
+	}
+	if _, _, _, err := u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01")); err != nil {
+		t.Fatalf("AckMessage: %v", err)
+	}
+
+	// The form is opened without the optional inputs
+	calls := repo.Calls("SubmitButtonAction")
+	if len(calls) != 1 || len(calls[0].Blocks) != 0 {
+		t.Errorf("SubmitButtonAction calls = %+v", calls)
+	}
+	message, err := repo.GetMessageByTimestamp(ctx, opened.MessageTimestamp, "C1")
+	if err != nil || message.UserACK != "U0ALICE01" {
+		t.Errorf("stored message = %+v, %v", message, err)
+	}
+}
+
+func TestAckMessageSubmitFailure(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+
+	repo.Fail("SubmitButtonAction", errSlackDown)
+	incident, result, _, err := u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01"))
+	if err != nil {
+		t.Fatalf("AckMessage: %v", err)
+	}
+	if result != "" || incident.IncidentID != "42" {
+		t.Errorf("AckMessage = %+v, %q", incident, result)
+	}
+	if calls := repo.Calls("SubmitButtonAction"); len(calls) != 0 {
+		t.Errorf("failed SubmitButtonAction recorded: %+v", calls)
+	}
+
+	// The acknowledgement is stored even though the form could not be shown
+	message, _ := repo.GetMessageByTimestamp(ctx, opened.MessageTimestamp, "C1")
+	if message.UserACK != "U0ALICE01" {
+		t.Errorf("UserACK = %q", message.UserACK)
+	}
+}
+
+func TestSubmitAckForm(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+	callback := ackCallback("C1", opened.MessageTimestamp, "U0ALICE01")
+	u.AckMessage(ctx, callback)
+
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		"root_cause": {"select": {SelectedOption: slack.OptionBlockObject{Value: "bad-deploy"}}},
+	}}
+	incident, value, err := u.SubmitAckForm(ctx, callback, opened.MessageTimestamp, "C1")
+	if err != nil {
+		t.Fatalf("SubmitAckForm: %v", err)
+	}
+	if value != "bad-deploy" || incident.RootCause != "bad-deploy" {
+		t.Fatalf("SubmitAckForm = %+v, %q", incident, value)
+	}
+
+	calls := repo.Calls("ReplaceMessage")
+	if len(calls) != 1 {
+		t.Fatalf("ReplaceMessage calls = %+v", calls)
+	}
+	if call := calls[0]; call.Channel != "C1" || call.Timestamp != opened.MessageTimestamp || call.Value != "bad-deploy" || call.Username != "<@U0ALICE01>" {
+		t.Errorf("ReplaceMessage call = %+v", call)
+	}
+}
+
+func TestSubmitAckFormFailures(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	opened, _ := u.ProcessIncident(ctx, testPayload("42", "open"))
+	callback := ackCallback("C1", opened.MessageTimestamp, "U0ALICE01")
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		"root_cause": {"input": {Value: "disk-full"}},
+	}}
+
+	// A failed Slack update keeps the stored root cause
+	repo.Fail("ReplaceMessage", errSlackDown)
+	incident, _, err := u.SubmitAckForm(ctx, callback, opened.MessageTimestamp, "C1")
+	if err != nil || incident.RootCause != "disk-full" {
+		t.Fatalf("SubmitAckForm = %+v, %v", incident, err)
+	}
+	if calls := repo.Calls("ReplaceMessage"); len(calls) != 0 {
+		t.Errorf("failed ReplaceMessage recorded: %+v", calls)
+	}
+
+	// A failed root cause update still refreshes the message with the stored one
+	repo.Fail("ReplaceMessage", nil)
+	repo.Fail("UpdateNewRelicIncidentByID", errSlackDown)
+	callback.View.State.Values["root_cause"] = map[string]slack.BlockAction{"input": {Value: "dns"}}
+	incident, value, err := u.SubmitAckForm(ctx, callback, opened.MessageTimestamp, "C1")
+	if err != nil || value != "dns" || incident.RootCause != "disk-full" {
+		t.Fatalf("SubmitAckForm = %+v, %q, %v", incident, value, err)
+	}
+	if calls := repo.Calls("ReplaceMessage"); len(calls) != 1 {
+		t.Errorf("ReplaceMessage calls = %+v", calls)
+	}
+}
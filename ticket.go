@@ -0,0 +1,373 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// EventTicketCreated is recorded when a ticket is opened for an incident.
+const EventTicketCreated = "ticket_created"
+
+// Ticket is an issue opened in an external ticket system.
+type Ticket struct {
+	Key      string `json:"key"`
+	URL      string `json:"url"`
+	Status   string `json:"status"`
+	Resolved bool   `json:"resolved"`
+}
+
+// TicketSystem opens and resolves tickets for incidents.
+type TicketSystem interface {
+	CreateTicket(ctx context.Context, incident entitySlack.Incident, summary, description string) (Ticket, error)
+	GetTicket(ctx context.Context, key string) (Ticket, error)
+	ResolveTicket(ctx context.Context, key, comment string) error
+}
+
+type ticketRepository interface {
+	InsertTicket(ctx context.Context, incidentID, channel string, ticket Ticket) error
+	GetTicket(ctx context.Context, incidentID, channel string) (Ticket, error)
+	DeleteTicket(ctx context.Context, incidentID, channel string) error
+}
+
+// WithTickets opens a ticket in system for every incident whose severity is at
+// least minSeverity, e.g. SEV2 or P2. An unknown minSeverity is logged and
+// only SEV1 incidents open tickets.
+func WithTickets(system TicketSystem, minSeverity string) Option {
+	return func(u *UseCase) {
+		severity, ok := ParseSeverity(minSeverity)
+		if !ok {
+			log.Errorf("Invalid ticket severity %q, only %s incidents open tickets", minSeverity, SEV1)
+			severity = SEV1
+		}
+
+		u.tickets = system
+		u.ticketSeverity = severity
+	}
+}
+
+// WithTicketStore replaces the default in-memory incident-to-ticket store,
+// e.g. with a durable repository.SQLRepository.
+func WithTicketStore(store ticketRepository) Option {
+	return func(u *UseCase) {
+		u.ticketStore = store
+	}
+}
+
+// MemoryTickets is an in-process ticketRepository. Tickets are lost on
+// restart; use a durable repository.SQLRepository in production.
+type MemoryTickets struct {
+	mu      sync.RWMutex
+	tickets map[incidentKey]Ticket
+}
+
+func NewMemoryTickets() *MemoryTickets {
+	return &MemoryTickets{
+		tickets: map[incidentKey]Ticket{},
+	}
+}
+
+func (m *MemoryTickets) InsertTicket(ctx context.Context, incidentID, channel string, ticket Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tickets[incidentKey{incidentID, channel}] = ticket
+	return nil
+}
+
+func (m *MemoryTickets) GetTicket(ctx context.Context, incidentID, channel string) (Ticket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ticket, ok := m.tickets[incidentKey{incidentID, channel}]
+	if !ok {
+		return Ticket{}, ErrNotFound
+	}
+	return ticket, nil
+}
+
+func (m *MemoryTickets) DeleteTicket(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tickets, incidentKey{incidentID, channel})
+	return nil
+}
+
+// severityAtLeast compares severities after normalizing vendor values such
+// as "critical" or "P2".
+func severityAtLeast(severity string, min Severity) bool {
+	return NormalizeSeverity(severity).Rank() >= min.Rank()
+}
+
+// openTicket opens a ticket for a newly registered incident above the
+// configured severity and posts its link in the incident thread.
+func (u *UseCase) openTicket(ctx context.Context, incident entitySlack.Incident) {
+	if u.tickets == nil || !severityAtLeast(incident.Severity, u.ticketSeverity) {
+		return
+	}
+	if _, err := u.ticketStore.GetTicket(ctx, incident.IncidentID, incident.Channel); err == nil {
+		return
+	}
+
+	summary := fmt.Sprintf("[%s] %s", NormalizeSeverity(incident.Severity), incident.Name)
+	description := fmt.Sprintf("%s\n\nIncident: %s\nSlack thread: %s\nStarted: %s", incident.Description, incident.URL, threadURL(incident), incident.StartTime.Format(time.RFC1123))
+	ticket, err := u.tickets.CreateTicket(ctx, incident, summary, description)
+	if err != nil {
+		log.Errorf("Failed create ticket for incident %s because: %s", incident.IncidentID, err)
+		return
+	}
+
+	if err := u.ticketStore.InsertTicket(ctx, incident.IncidentID, incident.Channel, ticket); err != nil {
+		log.Errorf("Error store ticket to database: %s", err)
+	}
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: incident.IncidentID,
+		Channel:    incident.Channel,
+		Type:       EventTicketCreated,
+		Actor:      "diary",
+		Detail:     ticket.Key,
+	})
+
+	message := fmt.Sprintf(":ticket: Ticket <%s|%s> opened for this incident", ticket.URL, ticket.Key)
+	_, _, err = u.slackRepo.ReplyMessageInThread(ctx, incident.Channel, message, u.GetColorStr(incident.Status, incident.Severity), incident.MessageTimestamp, incident.URL)
+	if err != nil {
+		log.Errorf("Failed send slack message to channel %s because: %s", incident.Channel, err)
+	}
+}
+
+// resolveTicket resolves the ticket of a closed incident.
+func (u *UseCase) resolveTicket(ctx context.Context, incident entitySlack.Incident) {
+	if u.tickets == nil {
+		return
+	}
+
+	ticket, err := u.ticketStore.GetTicket(ctx, incident.IncidentID, incident.Channel)
+	if err != nil || ticket.Resolved {
+		return
+	}
+
+	comment := fmt.Sprintf("Incident resolved at %s.", time.Now().Format(time.RFC1123))
+	if rootCause := strings.Replace(incident.RootCause, "-", " ", -1); rootCause != "" && rootCause != "null" {
+		comment += " Root cause: " + rootCause + "."
+	}
+	if err := u.tickets.ResolveTicket(ctx, ticket.Key, comment); err != nil {
+		log.Errorf("Failed resolve ticket %s because: %s", ticket.Key, err)
+		return
+	}
+
+	ticket.Resolved = true
+	if err := u.ticketStore.InsertTicket(ctx, incident.IncidentID, incident.Channel, ticket); err != nil {
+		log.Errorf("Error store ticket to database: %s", err)
+	}
+}
+
+// SyncTickets closes open incidents whose ticket was resolved in the ticket system.
+func (u *UseCase) SyncTickets(ctx context.Context) error {
+	if u.tickets == nil {
+		return nil
+	}
+
+	incidents, _, err := u.SearchIncidents(ctx, IncidentQuery{Status: []string{"open", "acknowledged"}, Limit: maxSearchLimit})
+	if err != nil {
+		return err
+	}
+
+	for _, incident := range incidents {
+		stored, err := u.ticketStore.GetTicket(ctx, incident.IncidentID, incident.Channel)
+		if err != nil {
+			continue
+		}
+
+		ticket, err := u.tickets.GetTicket(ctx, stored.Key)
+		if err != nil {
+			log.Errorf("Failed get ticket %s because: %s", stored.Key, err)
+			continue
+		}
+		if !ticket.Resolved {
+			continue
+		}
+
+		stored.Resolved, stored.Status = true, ticket.Status
+		if err := u.ticketStore.InsertTicket(ctx, incident.IncidentID, incident.Channel, stored); err != nil {
+			log.Errorf("Error store ticket to database: %s", err)
+		}
+
+		note := fmt.Sprintf(":ticket: Ticket <%s|%s> was resolved", stored.URL, stored.Key)
+		if _, err := u.applyState(ctx, incident, "closed", stored.Key, note); err != nil {
+			log.Errorf("Error store message to database: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// RunTicketSync syncs ticket resolutions every interval until ctx is done.
+func (u *UseCase) RunTicketSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := u.SyncTickets(ctx); err != nil {
+				log.Errorf("Failed sync tickets because: %s", err)
+			}
+		}
+	}
+}
+
+// JiraClient is a TicketSystem backed by the Jira REST API v2.
+type JiraClient struct {
+	BaseURL   string
+	Email     string
+	APIToken  string
+	Project   string
+	IssueType string
+	// DoneTransition is the workflow transition used to resolve tickets, "Done" by default.
+	DoneTransition string
+	Client         *http.Client
+}
+
+func (j *JiraClient) CreateTicket(ctx context.Context, incident entitySlack.Incident, summary, description string) (Ticket, error) {
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.Project},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     summary,
+			"description": description,
+			"labels":      []string{"diary", "incident-" + incident.IncidentID},
+		},
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue", body, &created); err != nil {
+		return Ticket{}, err
+	}
+
+	return Ticket{Key: created.Key, URL: j.browseURL(created.Key), Status: "open"}, nil
+}
+
+func (j *JiraClient) GetTicket(ctx context.Context, key string) (Ticket, error) {
+	var issue struct {
+		Fields struct {
+			Status struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+key+"?fields=status", nil, &issue); err != nil {
+		return Ticket{}, err
+	}
+
+	return Ticket{
+		Key:      key,
+		URL:      j.browseURL(key),
+		Status:   issue.Fields.Status.Name,
+		Resolved: issue.Fields.Status.StatusCategory.Key == "done",
+	}, nil
+}
+
+// ResolveTicket moves the issue through the DoneTransition and then comments
+// on it, so an issue that cannot be resolved gets no resolution comment.
+func (j *JiraClient) ResolveTicket(ctx context.Context, key, comment string) error {
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+key+"/transitions", nil, &transitions); err != nil {
+		return err
+	}
+
+	done := j.DoneTransition
+	if done == "" {
+		done = "Done"
+	}
+	transitionID := ""
+	for _, t := range transitions.Transitions {
+		if strings.EqualFold(t.Name, done) {
+			transitionID = t.ID
+			break
+		}
+	}
+	if transitionID == "" {
+		return fmt.Errorf("jira issue %s has no %q transition", key, done)
+	}
+
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/transitions", map[string]interface{}{
+		"transition": map[string]string{"id": transitionID},
+	}, nil); err != nil {
+		return err
+	}
+
+	return j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/comment", map[string]string{"body": comment}, nil)
+}
+
+func (j *JiraClient) browseURL(key string) string {
+	return strings.TrimRight(j.BaseURL, "/") + "/browse/" + key
+}
+
+func (j *JiraClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(j.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(j.Email, j.APIToken)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := j.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("jira %s %s responded with status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
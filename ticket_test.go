@@ -0,0 +1,173 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+)
+
+// recordingTickets is a TicketSystem remembering the incidents it opened tickets for.
+type recordingTickets struct {
+	opened []string
+}
+
+func (r *recordingTickets) CreateTicket(ctx context.Context, incident entitySlack.Incident, summary, description string) (Ticket, error) {
+	r.opened = append(r.opened, incident.IncidentID)
+	return Ticket{Key: "OPS-" + incident.IncidentID}, nil
+}
+
+func (r *recordingTickets) GetTicket(ctx context.Context, key string) (Ticket, error) {
+	return Ticket{Key: key}, nil
+}
+
+func (r *recordingTickets) ResolveTicket(ctx context.Context, key, comment string) error {
+	return nil
+}
+
+func TestWithTicketsSeverity(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		minSeverity string
+		want        []string
+	}{
+		{"P2", []string{"1", "2"}},
+		{"sev3", []string{"1", "2", "3"}},
+		// Unknown values only open tickets for SEV1 instead of falling back to SEV3
+		{"urgent", []string{"1"}},
+		{"", []string{"1"}},
+	} {
+		tickets := &recordingTickets{}
+		u, _ := newTestUseCase(t, WithTickets(tickets, tc.minSeverity))
+		if u.ticketSeverity.Rank() == 0 {
+			t.Errorf("WithTickets(%q) kept invalid severity %q", tc.minSeverity, u.ticketSeverity)
+		}
+
+		for id, severity := range map[string]string{"1": "critical", "2": "high", "3": "warning", "4": "low"} {
+			payload := testPayload(id, "open")
+			payload.Severity = severity
+			u.ProcessIncident(ctx, payload)
+		}
+
+		got := map[string]bool{}
+		for _, id := range tickets.opened {
+			got[id] = true
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("WithTickets(%q) opened tickets for %v, want %v", tc.minSeverity, tickets.opened, tc.want)
+			continue
+		}
+		for _, id := range tc.want {
+			if !got[id] {
+				t.Errorf("WithTickets(%q) opened tickets for %v, want %v", tc.minSeverity, tickets.opened, tc.want)
+			}
+		}
+	}
+}
+
+// fakeJira serves the Jira REST endpoints JiraClient uses and records the
+// requests it receives.
+type fakeJira struct {
+	mu          sync.Mutex
+	requests    []string
+	bodies      map[string]map[string]interface{}
+	transitions []map[string]string
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, token, ok := r.BasicAuth(); !ok || user != "bot@acme.test" || token != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	request := r.Method + " " + r.URL.Path
+	body := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, request)
+	f.bodies[request] = body
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch request {
+	case "POST /rest/api/2/issue":
+		json.NewEncoder(w).Encode(map[string]string{"key": "OPS-7"})
+	case "GET /rest/api/2/issue/OPS-7":
+		w.Write([]byte(`{"fields": {"status": {"name": "Done", "statusCategory": {"key": "done"}}}}`))
+	case "GET /rest/api/2/issue/OPS-7/transitions":
+		json.NewEncoder(w).Encode(map[string]interface{}{"transitions": f.transitions})
+	case "POST /rest/api/2/issue/OPS-7/transitions", "POST /rest/api/2/issue/OPS-7/comment":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeJira(t *testing.T, transitions ...map[string]string) (*fakeJira, *JiraClient) {
+	jira := &fakeJira{bodies: map[string]map[string]interface{}{}, transitions: transitions}
+	server := httptest.NewServer(jira)
+	t.Cleanup(server.Close)
+
+	return jira, &JiraClient{BaseURL: server.URL + "/", Email: "bot@acme.test", APIToken: "secret", Project: "OPS"}
+}
+
+func TestJiraClient(t *testing.T) {
+	ctx := context.Background()
+	jira, client := newFakeJira(t, map[string]string{"id": "11", "name": "In Progress"}, map[string]string{"id": "31", "name": "done"})
+
+	ticket, err := client.CreateTicket(ctx, entitySlack.Incident{IncidentID: "42"}, "CPU high", "cpu > 90%")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if ticket.Key != "OPS-7" || !strings.HasSuffix(ticket.URL, "/browse/OPS-7") || strings.Contains(ticket.URL, "//browse") {
+		t.Errorf("created ticket = %+v", ticket)
+	}
+	fields, _ := jira.bodies["POST /rest/api/2/issue"]["fields"].(map[string]interface{})
+	if fields["summary"] != "CPU high" || fields["issuetype"].(map[string]interface{})["name"] != "Bug" || fields["project"].(map[string]interface{})["key"] != "OPS" {
+		t.Errorf("create issue fields = %v", fields)
+	}
+
+	if ticket, err = client.GetTicket(ctx, "OPS-7"); err != nil || ticket.Status != "Done" || !ticket.Resolved {
+		t.Errorf("GetTicket = %+v, %v", ticket, err)
+	}
+
+	if err := client.ResolveTicket(ctx, "OPS-7", "Incident resolved."); err != nil {
+		t.Fatalf("ResolveTicket: %v", err)
+	}
+	want := []string{
+		"POST /rest/api/2/issue",
+		"GET /rest/api/2/issue/OPS-7",
+		"GET /rest/api/2/issue/OPS-7/transitions",
+		"POST /rest/api/2/issue/OPS-7/transitions",
+		"POST /rest/api/2/issue/OPS-7/comment",
+	}
+	if strings.Join(jira.requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", jira.requests, want)
+	}
+	if transition, _ := jira.bodies["POST /rest/api/2/issue/OPS-7/transitions"]["transition"].(map[string]interface{}); transition["id"] != "31" {
+		t.Errorf("transition = %v, want the done transition", transition)
+	}
+	if comment := jira.bodies["POST /rest/api/2/issue/OPS-7/comment"]["body"]; comment != "Incident resolved." {
+		t.Errorf("comment = %v", comment)
+	}
+}
+
+func TestJiraResolveWithoutTransition(t *testing.T) {
+	jira, client := newFakeJira(t, map[string]string{"id": "11", "name": "In Progress"})
+
+	if err := client.ResolveTicket(context.Background(), "OPS-7", "Incident resolved."); err == nil {
+		t.Fatal("ResolveTicket succeeded without a done transition")
+	}
+	for _, request := range jira.requests {
+		if request != "GET /rest/api/2/issue/OPS-7/transitions" {
+			t.Errorf("unexpected request %s; the ticket must not be commented on before it is resolved", request)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// Incident event types recorded in the append-only incident event log.
+const (
+	EventReceived     = "received"
+	EventStateChanged = "state_changed"
+	EventAcknowledged = "acknowledged"
+	EventRootCause    = "root_cause"
+	EventEscalated    = "escalated"
+)
+
+// IncidentEvent is a single immutable entry of an incident's history.
+type IncidentEvent struct {
+	IncidentID    string          `json:"incident_id"`
+	Channel       string          `json:"channel"`
+	Type          string          `json:"type"`
+	State         string          `json:"state,omitempty"`
+	PreviousState string          `json:"previous_state,omitempty"`
+	Actor         string          `json:"actor,omitempty"`
+	Detail        string          `json:"detail,omitempty"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	Stale         bool            `json:"stale,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// eventRepository stores the event log. Events belong to an incident in one
+// channel, like the incident rows themselves.
+type eventRepository interface {
+	InsertIncidentEvent(ctx context.Context, event IncidentEvent) error
+	GetIncidentEvents(ctx context.Context, incidentID, channel string) ([]IncidentEvent, error)
+	DeleteIncidentEvents(ctx context.Context, incidentID, channel string) error
+}
+
+// WithEventLog replaces the default in-memory incident event log, e.g. with
+// a durable repository.SQLRepository.
+func WithEventLog(events eventRepository) Option {
+	return func(u *UseCase) {
+		u.events = events
+	}
+}
+
+// MemoryEventLog is an in-process eventRepository. Events are lost on restart.
+type MemoryEventLog struct {
+	mu     sync.RWMutex
+	events map[incidentKey][]IncidentEvent
+}
+
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{
+		events: map[incidentKey][]IncidentEvent{},
+	}
+}
+
+func (m *MemoryEventLog) InsertIncidentEvent(ctx context.Context, event IncidentEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := incidentKey{event.IncidentID, event.Channel}
+	m.events[key] = append(m.events[key], event)
+	return nil
+}
+
+func (m *MemoryEventLog) GetIncidentEvents(ctx context.Context, incidentID, channel string) ([]IncidentEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := incidentKey{incidentID, channel}
+	events := make([]IncidentEvent, len(m.events[key]))
+	copy(events, m.events[key])
+	return events, nil
+}
+
+func (m *MemoryEventLog) DeleteIncidentEvents(ctx context.Context, incidentID, channel string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.events, incidentKey{incidentID, channel})
+	return nil
+}
+
+// GetIncidentTimeline returns every recorded event of an incident in a channel, oldest first.
+func (u *UseCase) GetIncidentTimeline(ctx context.Context, incidentID, channel string) ([]IncidentEvent, error) {
+	events, err := u.events.GetIncidentEvents(ctx, incidentID, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+
+	return events, nil
+}
+
+// RecordEscalation appends an escalation to the incident timeline and
+// publishes it to dashboard subscribers.
+func (u *UseCase) RecordEscalation(ctx context.Context, incidentID, channel, actor, detail string) error {
+	return u.appendEvent(ctx, IncidentEvent{
+		IncidentID: incidentID,
+		Channel:    channel,
+		Type:       EventEscalated,
+		Actor:      actor,
+		Detail:     detail,
+	})
+}
+
+func (u *UseCase) recordEvent(ctx context.Context, event IncidentEvent) {
+	if err := u.appendEvent(ctx, event); err != nil {
+		log.Errorf("Error store incident event to database: %s", err)
+	}
+}
+
+// appendEvent stores event and publishes it to dashboard subscribers.
+func (u *UseCase) appendEvent(ctx context.Context, event IncidentEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	err := u.events.InsertIncidentEvent(ctx, event)
+	u.broker.publish(event)
+	return err
+}
+
+// hasEvent reports whether the incident already has an event of the given type.
+func (u *UseCase) hasEvent(ctx context.Context, incidentID, channel, eventType string) bool {
+	events, err := u.events.GetIncidentEvents(ctx, incidentID, channel)
+	if err != nil {
+		log.Errorf("Error GET incident events on database: %s", err)
+		return false
+	}
+
+	for _, event := range events {
+		if event.Type == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *UseCase) recordReceived(ctx context.Context, data entitySlack.NewRelicReplyThread, occurredAt time.Time, stale bool) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("Failed encode incident payload because: %s", err)
+	}
+
+	u.recordEvent(ctx, IncidentEvent{
+		IncidentID: data.GetIncidentID(),
+		Channel:    data.GetChannel(),
+		Type:       EventReceived,
+		State:      data.GetState(),
+		Actor:      data.GetVendor(),
+		Payload:    payload,
+		Stale:      stale,
+		OccurredAt: occurredAt,
+	})
+}
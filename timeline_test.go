@@ -0,0 +1,102 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTimelineIsPerChannel(t *testing.T) {
+	ctx := context.Background()
+	u, _ := newTestUseCase(t)
+
+	inC1 := testPayload("42", "open")
+	inC2 := testPayload("42", "open")
+	inC2.Channel = "C2"
+	opened, _ := u.ProcessIncident(ctx, inC1)
+	u.ProcessIncident(ctx, inC2)
+
+	u.AckMessage(ctx, ackCallback("C1", opened.MessageTimestamp, "U0ALICE01"))
+
+	if !u.hasEvent(ctx, "42", "C1", EventAcknowledged) {
+		t.Error("C1 has no acknowledged event")
+	}
+	if u.hasEvent(ctx, "42", "C2", EventAcknowledged) {
+		t.Error("ack in C1 shows up in C2")
+	}
+
+	timeline, err := u.GetIncidentTimeline(ctx, "42", "C2")
+	if err != nil {
+		t.Fatalf("GetIncidentTimeline: %v", err)
+	}
+	for _, event := range timeline {
+		if event.Channel != "C2" {
+			t.Errorf("C2 timeline has event of channel %s: %+v", event.Channel, event)
+		}
+	}
+	if len(timeline) != 2 {
+		t.Errorf("C2 timeline has %d events, want received and state_changed", len(timeline))
+	}
+}
+
+func TestTimelineAPIRequiresChannel(t *testing.T) {
+	u, _ := newTestUseCase(t)
+	u.ProcessIncident(context.Background(), testPayload("42", "open"))
+
+	mux := http.NewServeMux()
+	u.RegisterRoutes(mux)
+
+	for target, want := range map[string]int{
+		"/incidents/42/timeline":            http.StatusBadRequest,
+		"/incidents/42/timeline?channel=C1": http.StatusOK,
+		"/incidents/42/timeline?channel=C2": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", target, rec.Code, want)
+		}
+	}
+}
+
+func TestRaisedSeverityIsEscalation(t *testing.T) {
+	ctx := context.Background()
+	u, repo := newTestUseCase(t)
+
+	low := testPayload("42", "open")
+	low.Severity = "low"
+	u.ProcessIncident(ctx, low)
+	u.ProcessIncident(ctx, low)
+	if u.hasEvent(ctx, "42", "C1", EventEscalated) {
+		t.Fatal("redelivery at the same severity is an escalation")
+	}
+
+	events := u.broker.subscribe()
+	defer u.broker.unsubscribe(events)
+	u.ProcessIncident(ctx, testPayload("42", "acknowledged"))
+
+	timeline, _ := u.GetIncidentTimeline(ctx, "42", "C1")
+	escalations := []IncidentEvent{}
+	for _, event := range timeline {
+		if event.Type == EventEscalated {
+			escalations = append(escalations, event)
+		}
+	}
+	if len(escalations) != 1 || escalations[0].Detail != "SEV4 to SEV1" || escalations[0].Actor != "newrelic" {
+		t.Fatalf("escalations = %+v", escalations)
+	}
+	if incident, _ := repo.GetNewRelicIncidentByID(ctx, "42", "C1"); incident.Severity != string(SEV1) {
+		t.Errorf("severity after escalation = %s, want SEV1", incident.Severity)
+	}
+
+	published := false
+	for len(events) > 0 {
+		if event := <-events; event.Type == EventEscalated {
+			published = true
+		}
+	}
+	if !published {
+		t.Error("escalation not published to dashboard subscribers")
+	}
+}
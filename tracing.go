@@ -0,0 +1,192 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+	entitySlack "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/entity/slack"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/tokopedia/captainmarvel/cloud-platform-diary/internal/usecase/slack"
+
+// Span attribute keys shared by every diary span.
+var (
+	attrIncidentID = attribute.Key("diary.incident.id")
+	attrChannel    = attribute.Key("diary.slack.channel")
+	attrState      = attribute.Key("diary.incident.state")
+	attrMessageTs  = attribute.Key("diary.slack.message_ts")
+)
+
+// propagator carries W3C trace context and baggage across HTTP calls.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewOTLPTracerProvider exports spans over OTLP/gRPC to endpoint (e.g. a local
+// collector on "localhost:4317") and installs it, with W3C trace context
+// propagation, as the global provider. Tests can use an sdktrace provider with
+// tracetest.NewInMemoryExporter and WithTracerProvider instead.
+func NewOTLPTracerProvider(ctx context.Context, endpoint string, insecure bool) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("cloud-platform-diary"))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+
+	return tp, nil
+}
+
+// WithTracerProvider traces the UseCase and its repository with tp instead of
+// the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(u *UseCase) {
+		u.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// TraceHandler extracts the incoming W3C trace context and starts a server
+// span, so spans of the webhook-to-Slack flow join the caller's trace even
+// when no global propagator is installed. opts are applied after the
+// defaults, e.g. otelhttp.WithTracerProvider.
+func TraceHandler(h http.Handler, operation string, opts ...otelhttp.Option) http.Handler {
+	return otelhttp.NewHandler(h, operation, append([]otelhttp.Option{otelhttp.WithPropagators(propagator)}, opts...)...)
+}
+
+func (u *UseCase) startSpan(ctx context.Context, name, incidentID, channel string) (context.Context, trace.Span) {
+	return u.tracer.Start(ctx, name, trace.WithAttributes(attrIncidentID.String(incidentID), attrChannel.String(channel)))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedRepository wraps every repository and Slack API call in a span.
+type tracedRepository struct {
+	next   slackRepository
+	tracer trace.Tracer
+}
+
+func (r *tracedRepository) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "slackRepository."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func (r *tracedRepository) GetNewRelicIncident(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	ctx, span := r.start(ctx, "GetNewRelicIncident", attrIncidentID.String(incidentID), attrChannel.String(channel))
+	incident, err := r.next.GetNewRelicIncident(ctx, incidentID, channel)
+	endSpan(span, err)
+	return incident, err
+}
+
+func (r *tracedRepository) GetNewRelicIncidentByID(ctx context.Context, incidentID, channel string) (entitySlack.Incident, error) {
+	ctx, span := r.start(ctx, "GetNewRelicIncidentByID", attrIncidentID.String(incidentID), attrChannel.String(channel))
+	incident, err := r.next.GetNewRelicIncidentByID(ctx, incidentID, channel)
+	endSpan(span, err)
+	return incident, err
+}
+
+func (r *tracedRepository) GetNewRelicIncidentByMsgTimestamp(ctx context.Context, incidentID, messageTimestamp string) (entitySlack.Incident, error) {
+	ctx, span := r.start(ctx, "GetNewRelicIncidentByMsgTimestamp", attrIncidentID.String(incidentID), attrMessageTs.String(messageTimestamp))
+	incident, err := r.next.GetNewRelicIncidentByMsgTimestamp(ctx, incidentID, messageTimestamp)
+	endSpan(span, err)
+	return incident, err
+}
+
+func (r *tracedRepository) InsertNewRelicIncident(ctx context.Context, incidentID string, conditionID int, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels string, startTime, recoverTime time.Time) error {
+	ctx, span := r.start(ctx, "InsertNewRelicIncident", attrIncidentID.String(incidentID), attrChannel.String(channel), attrState.String(status))
+	err := r.next.InsertNewRelicIncident(ctx, incidentID, conditionID, name, url, description, owner, generatedBy, status, severity, rootCause, channel, labels, startTime, recoverTime)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracedRepository) UpdateNewRelicIncidentStatusByID(ctx context.Context, status, messageTimestamp, channel, incidentID string, recoverTime time.Time) error {
+	ctx, span := r.start(ctx, "UpdateNewRelicIncidentStatusByID", attrIncidentID.String(incidentID), attrChannel.String(channel), attrState.String(status))
+	err := r.next.UpdateNewRelicIncidentStatusByID(ctx, status, messageTimestamp, channel, incidentID, recoverTime)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracedRepository) UpdateNewRelicIncidentByID(ctx context.Context, rootCause, incidentID string) error {
+	ctx, span := r.start(ctx, "UpdateNewRelicIncidentByID", attrIncidentID.String(incidentID))
+	err := r.next.UpdateNewRelicIncidentByID(ctx, rootCause, incidentID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracedRepository) InsertMessage(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, incidentID string) error {
+	ctx, span := r.start(ctx, "InsertMessage", attrIncidentID.String(incidentID), attrMessageTs.String(messageTimestamp))
+	err := r.next.InsertMessage(ctx, triggerID, workspace, userACK, messageTimestamp, incidentID)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracedRepository) UpdateMessageByTimestamp(ctx context.Context, triggerID, workspace, userACK, messageTimestamp, channel string) error {
+	ctx, span := r.start(ctx, "UpdateMessageByTimestamp", attrChannel.String(channel), attrMessageTs.String(messageTimestamp))
+	err := r.next.UpdateMessageByTimestamp(ctx, triggerID, workspace, userACK, messageTimestamp, channel)
+	endSpan(span, err)
+	return err
+}
+
+func (r *tracedRepository) GetMessageByTimestamp(ctx context.Context, messageTimestamp, channel string) (entitySlack.Message, error) {
+	ctx, span := r.start(ctx, "GetMessageByTimestamp", attrChannel.String(channel), attrMessageTs.String(messageTimestamp))
+	message, err := r.next.GetMessageByTimestamp(ctx, messageTimestamp, channel)
+	endSpan(span, err)
+	return message, err
+}
+
+func (r *tracedRepository) SendMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	ctx, span := r.start(ctx, "SendMessage", attrChannel.String(channel))
+	respChannel, ts, err := r.next.SendMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+	span.SetAttributes(attrMessageTs.String(ts))
+	endSpan(span, err)
+	return respChannel, ts, err
+}
+
+func (r *tracedRepository) UpdateMessage(ctx context.Context, channel, message, color, messageTimestamp, vendor, url string) (string, string, error) {
+	ctx, span := r.start(ctx, "UpdateMessage", attrChannel.String(channel), attrMessageTs.String(messageTimestamp))
+	respChannel, ts, err := r.next.UpdateMessage(ctx, channel, message, color, messageTimestamp, vendor, url)
+	endSpan(span, err)
+	return respChannel, ts, err
+}
+
+func (r *tracedRepository) ReplyMessageInThread(ctx context.Context, channel, message, color, messageTimestamp, url string) (string, string, error) {
+	ctx, span := r.start(ctx, "ReplyMessageInThread", attrChannel.String(channel), attrMessageTs.String(messageTimestamp))
+	respChannel, ts, err := r.next.ReplyMessageInThread(ctx, channel, message, color, messageTimestamp, url)
+	endSpan(span, err)
+	return respChannel, ts, err
+}
+
+// SubmitButtonAction and ReplaceMessage take no context; the use case wraps
+// them in spans of its own.
+
+func (r *tracedRepository) SubmitButtonAction(blockActions []*slack.BlockAction, options []string, channel, messageTimestamp, triggerID, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	return r.next.SubmitButtonAction(blockActions, options, channel, messageTimestamp, triggerID, title, message, color, username, url, replaceOriginal)
+}
+
+func (r *tracedRepository) ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url string, replaceOriginal bool) (string, error) {
+	return r.next.ReplaceMessage(channel, messageTimestamp, value, title, message, color, username, url, replaceOriginal)
+}
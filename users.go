@@ -0,0 +1,147 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/tokopedia/tdk/go/log"
+)
+
+// userCacheTTL is how long a users.info lookup is reused.
+const userCacheTTL = time.Hour
+
+// userIDPattern matches Slack user IDs (U…, or W… on Enterprise Grid).
+var userIDPattern = regexp.MustCompile(`^[UW][A-Z0-9]{6,}$`)
+
+// mentionPattern matches user mentions in Slack message text, e.g. <@U123|name>.
+var mentionPattern = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// SlackUser is the resolved profile of a Slack user ID.
+type SlackUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email,omitempty"`
+}
+
+// userRepository is implemented by repositories that can call users.info.
+type userRepository interface {
+	GetUserInfo(ctx context.Context, userID string) (*slack.User, error)
+}
+
+type cachedUser struct {
+	user      SlackUser
+	fetchedAt time.Time
+}
+
+// userCache memoizes users.info lookups for userCacheTTL.
+type userCache struct {
+	mu    sync.Mutex
+	users map[string]cachedUser
+}
+
+func newUserCache() *userCache {
+	return &userCache{
+		users: map[string]cachedUser{},
+	}
+}
+
+func (c *userCache) get(id string) (SlackUser, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.users[id]
+	if !ok || time.Since(cached.fetchedAt) > userCacheTTL {
+		return SlackUser{}, false
+	}
+	return cached.user, true
+}
+
+func (c *userCache) put(user SlackUser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.users[user.ID] = cachedUser{user: user, fetchedAt: time.Now()}
+}
+
+// isUserID reports whether s is a Slack user ID rather than a legacy handle.
+func isUserID(s string) bool {
+	return userIDPattern.MatchString(s)
+}
+
+// mention renders a Slack user ID as a mention; legacy handles are returned as is.
+func mention(user string) string {
+	if isUserID(user) {
+		return fmt.Sprintf("<@%s>", user)
+	}
+
+	return user
+}
+
+// actorOf identifies the user of an interaction by ID, falling back to the
+// deprecated username for payloads without one.
+func actorOf(user slack.User) string {
+	if user.ID != "" {
+		return user.ID
+	}
+
+	return user.Name
+}
+
+// ResolveUser returns the profile of a Slack user ID through a cached users.info lookup.
+func (u *UseCase) ResolveUser(ctx context.Context, userID string) (SlackUser, error) {
+	if !isUserID(userID) {
+		return SlackUser{ID: userID, Name: userID, DisplayName: userID}, nil
+	}
+	if user, ok := u.users.get(userID); ok {
+		return user, nil
+	}
+
+	lookup, ok := u.baseRepo.(userRepository)
+	if !ok {
+		return SlackUser{ID: userID, Name: userID, DisplayName: userID}, nil
+	}
+
+	info, err := lookup.GetUserInfo(ctx, userID)
+	if err != nil {
+		return SlackUser{ID: userID, Name: userID, DisplayName: userID}, err
+	}
+
+	user := SlackUser{
+		ID:          info.ID,
+		Name:        info.Name,
+		DisplayName: info.Profile.DisplayName,
+		Email:       info.Profile.Email,
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = info.RealName
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = info.Name
+	}
+	u.users.put(user)
+
+	return user, nil
+}
+
+// plainMentions replaces <@U123> mentions in text with @display names.
+func (u *UseCase) plainMentions(ctx context.Context, text string) string {
+	return mentionPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return "@" + u.displayName(ctx, mentionPattern.FindStringSubmatch(m)[1])
+	})
+}
+
+// displayName resolves a user ID for plain-text output such as postmortems,
+// where Slack mentions do not render.
+func (u *UseCase) displayName(ctx context.Context, user string) string {
+	resolved, err := u.ResolveUser(ctx, user)
+	if err != nil {
+		log.Errorf("Failed resolve slack user %s because: %s", user, err)
+	}
+
+	return resolved.DisplayName
+}